@@ -374,6 +374,9 @@ var importCmd = &cobra.Command{
 				if hostRecord, err := restClient.GetHostByIP(host.IP); err == nil {
 					hostidMap[host.Hostid] = hostRecord.Hostid
 					continue //already exists
+				} else if !rest.IsNotFound(err) {
+					log.Printf("Failed to look up Host %s: %v\n", host.IP, err)
+					continue
 				}
 				task, err := restClient.JoinHost(viper.GetString("user"), viper.GetString("password"), host.IP)
 				if err != nil {
@@ -471,6 +474,9 @@ var importCmd = &cobra.Command{
 			for _, realm := range data.Realms {
 				if _, err := restClient.GetRealm(realm.Name); err == nil {
 					continue //already exists
+				} else if !rest.IsNotFound(err) {
+					log.Printf("Failed to look up realm %s: %v\n", realm.Name, err)
+					continue
 				}
 				fmt.Printf("Adding realm %s\n", realm.Name)
 				if realm.ServiceAccount != nil {
@@ -492,6 +498,9 @@ var importCmd = &cobra.Command{
 				}
 				if _, err := restClient.GetStoragePool(storagePool.ID); err == nil {
 					continue //already exists
+				} else if !rest.IsNotFound(err) {
+					log.Printf("Failed to look up storage pool %s: %v\n", storagePool.Name, err)
+					continue
 				}
 				fmt.Printf("Adding storage pool %s\n", storagePool.Name)
 				if storagePool.Type == "cifs" && storagePool.Password != "" {
@@ -517,6 +526,9 @@ var importCmd = &cobra.Command{
 			for _, profile := range data.Profiles {
 				if _, err := restClient.GetProfile(profile.ID); err == nil {
 					continue //already exists
+				} else if !rest.IsNotFound(err) {
+					log.Printf("Failed to look up profile %s: %v\n", profile.Name, err)
+					continue
 				}
 				if profile.UserVolumes != nil && profile.UserVolumes.Repository != "" && profile.UserVolumes.Repository == oldSharedStorageId {
 					if newSharedStorageId == "" {
@@ -569,6 +581,9 @@ var importCmd = &cobra.Command{
 			for _, template := range data.Templates {
 				if _, err := restClient.GetTemplate(template.Name); err == nil {
 					continue //already exists
+				} else if !rest.IsNotFound(err) {
+					log.Printf("Failed to look up template %s: %v\n", template.Name, err)
+					continue
 				}
 				foundDisks := true
 				for i, disk := range template.Disks {
@@ -616,6 +631,9 @@ var importCmd = &cobra.Command{
 			for _, pool := range data.Pools {
 				if _, err := restClient.GetPool(pool.ID); err == nil {
 					continue //already exists
+				} else if !rest.IsNotFound(err) {
+					log.Printf("Failed to look up guest pool %s: %v\n", pool.Name, err)
+					continue
 				}
 				if !viper.GetBool("import-standalone") && pool.Type == "standalone" {
 					continue //Skip standalone vms by default in case they are still running on the old cluster
@@ -683,6 +701,9 @@ var importCmd = &cobra.Command{
 				}
 				if _, err := restClient.GetGuest(guest.Name); err == nil {
 					continue //already exists
+				} else if !rest.IsNotFound(err) {
+					log.Printf("Failed to look up guest %s: %v\n", guest.Name, err)
+					continue
 				}
 
 				fmt.Printf("Adding external guest %s\n", guest.Name)
@@ -704,6 +725,9 @@ var importCmd = &cobra.Command{
 				}
 				if _, err := restClient.GetUser(user.ID); err == nil {
 					continue //already exists
+				} else if !rest.IsNotFound(err) {
+					log.Printf("Failed to look up user %s: %v\n", user.ID, err)
+					continue
 				}
 				_, err := user.Create(restClient)
 				if err != nil {
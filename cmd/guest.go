@@ -1,13 +1,21 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
 
 	"github.com/gocarina/gocsv"
 	"github.com/google/go-cmp/cmp"
 	"github.com/hive-io/hive-go-client/rest"
+	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -66,21 +74,17 @@ var guestDeleteCmd = &cobra.Command{
 }
 
 var guestRefreshCmd = &cobra.Command{
-	Use:   "refresh",
+	Use:   "refresh [Name]",
 	Short: "rebuild a guest with the latest pool settings",
-	Args:  cobra.ExactArgs(1),
+	Long:  guestSelectorHelp,
+	Args:  cobra.MaximumNArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindGuestSelectorFlags(cmd)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		var err error
-		guest, err := restClient.GetGuest(args[0])
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		err = guest.Refresh(restClient)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
+		runGuestOperation(args, func(ctx context.Context, guest *rest.Guest) error {
+			return guest.Refresh(restClient)
+		})
 	},
 }
 
@@ -178,54 +182,42 @@ var guestUpdateCmd = &cobra.Command{
 var guestPoweronCmd = &cobra.Command{
 	Use:   "poweron [Name]",
 	Short: "power on guest",
-	Args:  cobra.ExactArgs(1),
+	Long:  guestSelectorHelp,
+	Args:  cobra.MaximumNArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindGuestSelectorFlags(cmd)
+		bindGuestPowerFlags(cmd)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		guest, err := restClient.GetGuest(args[0])
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		guest.Poweron(restClient)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
+		runGuestOperation(args, guestPowerOperation((*rest.Guest).Poweron, (*rest.Guest).PoweronAndWait))
 	},
 }
 
 var guestPoweroffCmd = &cobra.Command{
 	Use:   "poweroff [Name]",
 	Short: "force power off guest",
-	Args:  cobra.ExactArgs(1),
+	Long:  guestSelectorHelp,
+	Args:  cobra.MaximumNArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindGuestSelectorFlags(cmd)
+		bindGuestPowerFlags(cmd)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		guest, err := restClient.GetGuest(args[0])
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		guest.Poweroff(restClient)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
+		runGuestOperation(args, guestPowerOperation((*rest.Guest).Poweroff, (*rest.Guest).PoweroffAndWait))
 	},
 }
 
 var guestRebootCmd = &cobra.Command{
 	Use:   "reboot [Name]",
 	Short: "reboot guest",
-	Args:  cobra.ExactArgs(1),
+	Long:  guestSelectorHelp,
+	Args:  cobra.MaximumNArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindGuestSelectorFlags(cmd)
+		bindGuestPowerFlags(cmd)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		guest, err := restClient.GetGuest(args[0])
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		guest.Reboot(restClient)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
+		runGuestOperation(args, guestPowerOperation((*rest.Guest).Reboot, (*rest.Guest).RebootAndWait))
 	},
 }
 
@@ -250,36 +242,29 @@ var guestReleaseCmd = &cobra.Command{
 var guestResetCmd = &cobra.Command{
 	Use:   "reset [Name]",
 	Short: "force reset guest",
-	Args:  cobra.ExactArgs(1),
+	Long:  guestSelectorHelp,
+	Args:  cobra.MaximumNArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindGuestSelectorFlags(cmd)
+		bindGuestPowerFlags(cmd)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		guest, err := restClient.GetGuest(args[0])
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		guest.Reset(restClient)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
+		runGuestOperation(args, guestPowerOperation((*rest.Guest).Reset, (*rest.Guest).ResetAndWait))
 	},
 }
 
 var guestShutdownCmd = &cobra.Command{
 	Use:   "shutdown [Name]",
 	Short: "shutdown guest",
-	Args:  cobra.ExactArgs(1),
+	Long:  guestSelectorHelp,
+	Args:  cobra.MaximumNArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindGuestSelectorFlags(cmd)
+		bindGuestPowerFlags(cmd)
+		viper.BindPFlag("force", cmd.Flags().Lookup("force"))
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		guest, err := restClient.GetGuest(args[0])
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		guest.Shutdown(restClient)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
+		runGuestOperation(args, guestPowerOperation((*rest.Guest).Shutdown, (*rest.Guest).ShutdownAndWait))
 	},
 }
 
@@ -354,14 +339,220 @@ var guestMigrateCmd = &cobra.Command{
 	PreRun: func(cmd *cobra.Command, args []string) {
 		cmd.MarkFlagRequired("hostid")
 		viper.BindPFlag("hostid", cmd.Flags().Lookup("hostid"))
+		viper.BindPFlag("wait", cmd.Flags().Lookup("wait"))
+		viper.BindPFlag("timeout", cmd.Flags().Lookup("timeout"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		guest, err := restClient.GetGuest(args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if !viper.GetBool("wait") {
+			err = guest.Migrate(restClient, viper.GetString("hostid"))
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			return
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		bar := progressbar.NewOptions(100,
+			progressbar.OptionFullWidth(),
+			progressbar.OptionSetPredictTime(false),
+			progressbar.OptionSetDescription("Migrating "+guest.Name))
+		err = guest.MigrateAndWait(ctx, restClient, viper.GetString("hostid"), viper.GetDuration("timeout"), func(percent int) {
+			bar.Set(percent)
+		})
+		fmt.Println("")
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var guestSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "guest snapshot operations",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Usage()
+		os.Exit(0)
 	},
+}
+
+var guestSnapshotListCmd = &cobra.Command{
+	Use:   "list [GuestName]",
+	Short: "list guest snapshots",
+	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		guest, err := restClient.GetGuest(args[0])
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		guest.Migrate(restClient, viper.GetString("hostid"))
+		snapshots, err := guest.ListSnapshots(restClient)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println(formatString(snapshots))
+	},
+}
+
+var guestSnapshotCreateCmd = &cobra.Command{
+	Use:   "create [GuestName] [SnapshotName]",
+	Short: "create a guest snapshot",
+	Args:  cobra.ExactArgs(2),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindTaskFlags(cmd)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		guest, err := restClient.GetGuest(args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		handleTask(guest.CreateSnapshot(restClient, args[1]))
+	},
+}
+
+var guestSnapshotRevertCmd = &cobra.Command{
+	Use:   "revert [GuestName] [SnapshotName]",
+	Short: "revert a guest to a snapshot",
+	Args:  cobra.ExactArgs(2),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindTaskFlags(cmd)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		guest, err := restClient.GetGuest(args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		handleTask(guest.RevertSnapshot(restClient, args[1]))
+	},
+}
+
+var guestSnapshotDeleteCmd = &cobra.Command{
+	Use:   "delete [GuestName] [SnapshotName]",
+	Short: "delete a guest snapshot",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		guest, err := restClient.GetGuest(args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = guest.DeleteSnapshot(restClient, args[1])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var guestConsoleCmd = &cobra.Command{
+	Use:   "console [Name]",
+	Short: "proxy a guest console to a local port for a vnc viewer",
+	Long: `Listen on a local port and forward each connection to the guest console until interrupted
+Connect any VNC viewer to the printed address
+
+Example:
+hioctl guest console win10-1 --local-port 5901
+vncviewer 127.0.0.1:5901
+`,
+	Args: cobra.ExactArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlag("listen", cmd.Flags().Lookup("listen"))
+		viper.BindPFlag("local-port", cmd.Flags().Lookup("local-port"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		guest, err := restClient.GetGuest(args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		listener, err := net.Listen("tcp", net.JoinHostPort(viper.GetString("listen"), viper.GetString("local-port")))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		context.AfterFunc(ctx, func() { listener.Close() })
+		fmt.Fprintf(os.Stderr, "console for %s listening on %s\n", guest.Name, listener.Addr())
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			go proxyGuestConsole(ctx, guest, conn)
+		}
+	},
+}
+
+// proxyGuestConsole copies data between conn and a new console connection until either side closes
+func proxyGuestConsole(ctx context.Context, guest *rest.Guest, conn net.Conn) {
+	defer conn.Close()
+	console, err := guest.OpenConsole(ctx, restClient)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	defer console.Close()
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+		console.Close()
+	})
+	defer stop()
+	done := make(chan struct{})
+	go func() {
+		io.Copy(console, conn)
+		console.Close()
+		close(done)
+	}()
+	io.Copy(conn, console)
+	conn.Close()
+	<-done
+}
+
+var guestScreenshotCmd = &cobra.Command{
+	Use:   "screenshot [Name]",
+	Short: "save a png screenshot of the guest console",
+	Args:  cobra.ExactArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlag("output", cmd.Flags().Lookup("output"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		guest, err := restClient.GetGuest(args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		image, err := guest.Screenshot(restClient)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		output := viper.GetString("output")
+		if output == "" {
+			output = guest.Name + ".png"
+		}
+		if output == "-" {
+			_, err = os.Stdout.Write(image)
+		} else {
+			err = os.WriteFile(output, image, 0644)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 	},
 }
 
@@ -435,6 +626,9 @@ test2,10.0.0.2,user2,TEST
 					os.Exit(1)
 				}
 				continue
+			} else if !rest.IsNotFound(err) {
+				fmt.Println(err)
+				os.Exit(1)
 			}
 			if _, err := guest.Create(restClient); err != nil {
 				fmt.Println(err)
@@ -502,6 +696,179 @@ func initBackupStorageFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP("storage-name", "n", "", "Storage Pool Name")
 }
 
+const guestSelectorHelp = `Operate on a single guest by name, or on every guest matching the selector flags.
+The name may be a glob such as "win10-*" to select guests by name.
+When guests are selected, a result is printed for each guest and the command exits with an error if any failed.
+--format accepts table in addition to json, yaml and json-compact for the results
+
+Example:
+hioctl guest poweroff --pool win10 --tag lab --state ready --dry-run
+`
+
+func addGuestSelectorFlags(cmd *cobra.Command) {
+	cmd.Flags().String("pool", "", "select guests in a pool by name or id")
+	cmd.Flags().String("hostid", "", "select guests running on a host")
+	cmd.Flags().StringSlice("tag", []string{}, "select guests with a tag, may be repeated")
+	cmd.Flags().StringSlice("state", []string{}, "select guests in a state, may be repeated")
+	cmd.Flags().Int("parallel", 5, "number of guests to operate on at once")
+	cmd.Flags().Bool("dry-run", false, "list the selected guests without changing them")
+}
+
+func bindGuestSelectorFlags(cmd *cobra.Command) {
+	viper.BindPFlag("pool", cmd.Flags().Lookup("pool"))
+	viper.BindPFlag("hostid", cmd.Flags().Lookup("hostid"))
+	viper.BindPFlag("tag", cmd.Flags().Lookup("tag"))
+	viper.BindPFlag("state", cmd.Flags().Lookup("state"))
+	viper.BindPFlag("parallel", cmd.Flags().Lookup("parallel"))
+	viper.BindPFlag("dry-run", cmd.Flags().Lookup("dry-run"))
+}
+
+func addGuestPowerFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("wait", false, "wait for the guests to reach the resulting state")
+	cmd.Flags().Duration("timeout", 0, "maximum time to wait, 0 waits forever")
+	cmd.Flags().String("wait-state", "", "state to wait for instead of running or stopped, for example ready")
+}
+
+func bindGuestPowerFlags(cmd *cobra.Command) {
+	viper.BindPFlag("wait", cmd.Flags().Lookup("wait"))
+	viper.BindPFlag("timeout", cmd.Flags().Lookup("timeout"))
+	viper.BindPFlag("wait-state", cmd.Flags().Lookup("wait-state"))
+}
+
+// guestPowerOperation returns an operation for runGuestOperation that calls actionAndWait with --wait and action without
+func guestPowerOperation(action func(*rest.Guest, *rest.Client) error, actionAndWait func(*rest.Guest, context.Context, *rest.Client, rest.GuestPowerOptions) error) func(context.Context, *rest.Guest) error {
+	if !viper.GetBool("wait") {
+		if viper.GetBool("force") {
+			fmt.Println("Error: --force requires --wait")
+			os.Exit(1)
+		}
+		return func(ctx context.Context, guest *rest.Guest) error {
+			return action(guest, restClient)
+		}
+	}
+	options := rest.GuestPowerOptions{
+		Timeout: viper.GetDuration("timeout"),
+		State:   viper.GetString("wait-state"),
+		Force:   viper.GetBool("force"),
+		OnForce: func(guest *rest.Guest) {
+			fmt.Fprintf(os.Stderr, "guest %s did not shut down in time, powering off\n", guest.Name)
+		},
+	}
+	if options.Force && options.Timeout <= 0 {
+		fmt.Println("Error: --force requires --timeout")
+		os.Exit(1)
+	}
+	return func(ctx context.Context, guest *rest.Guest) error {
+		return actionAndWait(guest, ctx, restClient, options)
+	}
+}
+
+// selectGuests returns the guests selected by args and the selector flags.
+// bulk is false when args names a single guest and no selector flags are set
+func selectGuests(args []string) (guests []rest.Guest, bulk bool, err error) {
+	selector := rest.GuestSelector{
+		Hostid: viper.GetString("hostid"),
+		Tags:   viper.GetStringSlice("tag"),
+		States: viper.GetStringSlice("state"),
+	}
+	if len(args) == 1 {
+		selector.Name = args[0]
+	}
+	if pool := viper.GetString("pool"); pool != "" {
+		p, err := restClient.GetPoolByName(pool)
+		if rest.IsNotFound(err) {
+			p, err = restClient.GetPool(pool)
+		}
+		if err != nil {
+			return nil, true, err
+		}
+		selector.PoolID = p.ID
+	}
+	if selector.Empty() {
+		return nil, false, errors.New("a guest name or selector flag is required")
+	}
+	single := selector.PoolID == "" && selector.Hostid == "" && len(selector.Tags) == 0 && len(selector.States) == 0
+	if single && !strings.ContainsAny(selector.Name, "*?[") {
+		guest, err := restClient.GetGuest(selector.Name)
+		if err != nil {
+			return nil, false, err
+		}
+		return []rest.Guest{*guest}, false, nil
+	}
+	guests, err = restClient.SelectGuests(selector)
+	return guests, true, err
+}
+
+// guestResult is printed for each guest of a bulk operation
+type guestResult struct {
+	Name   string `json:"name"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// runGuestOperation runs operation on the selected guests and exits with an error if any guest failed
+func runGuestOperation(args []string, operation func(context.Context, *rest.Guest) error) {
+	format := viper.GetString("format")
+	switch format {
+	case "json", "json-compact", "yaml", "table":
+	default:
+		fmt.Println("Error: Unsupported format")
+		os.Exit(1)
+	}
+	guests, bulk, err := selectGuests(args)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if viper.GetBool("dry-run") {
+		results := make([]guestResult, 0, len(guests))
+		for _, guest := range guests {
+			results = append(results, guestResult{Name: guest.Name, Result: "skipped"})
+		}
+		printGuestResults(results, format)
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	bulkResults := restClient.BulkGuestOperation(ctx, guests, viper.GetInt("parallel"), operation)
+	if !bulk {
+		if err := bulkResults[0].Err; err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	results := make([]guestResult, 0, len(bulkResults))
+	failed := 0
+	for _, result := range bulkResults {
+		if result.Err != nil {
+			failed++
+			results = append(results, guestResult{Name: result.Guest.Name, Result: "failed", Error: result.Err.Error()})
+			continue
+		}
+		results = append(results, guestResult{Name: result.Guest.Name, Result: "ok"})
+	}
+	printGuestResults(results, format)
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %d of %d guests failed\n", failed, len(results))
+		os.Exit(1)
+	}
+}
+
+func printGuestResults(results []guestResult, format string) {
+	if format != "table" {
+		fmt.Println(formatString(results))
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tRESULT\tERROR")
+	for _, result := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", result.Name, result.Result, result.Error)
+	}
+	w.Flush()
+}
+
 func init() {
 	RootCmd.AddCommand(guestCmd)
 
@@ -510,6 +877,7 @@ func init() {
 	guestAssignCmd.Flags().String("guest-realm", "", "user's realm")
 
 	guestCmd.AddCommand(guestRefreshCmd)
+	addGuestSelectorFlags(guestRefreshCmd)
 	guestCmd.AddCommand(guestDeleteCmd)
 	guestCmd.AddCommand(guestDiffCmd)
 	guestCmd.AddCommand(guestGetCmd)
@@ -518,11 +886,22 @@ func init() {
 	addListFlags(guestListCmd)
 
 	guestCmd.AddCommand(guestPoweronCmd)
+	addGuestSelectorFlags(guestPoweronCmd)
+	addGuestPowerFlags(guestPoweronCmd)
 	guestCmd.AddCommand(guestPoweroffCmd)
+	addGuestSelectorFlags(guestPoweroffCmd)
+	addGuestPowerFlags(guestPoweroffCmd)
 	guestCmd.AddCommand(guestRebootCmd)
+	addGuestSelectorFlags(guestRebootCmd)
+	addGuestPowerFlags(guestRebootCmd)
 	guestCmd.AddCommand(guestReleaseCmd)
 	guestCmd.AddCommand(guestResetCmd)
+	addGuestSelectorFlags(guestResetCmd)
+	addGuestPowerFlags(guestResetCmd)
 	guestCmd.AddCommand(guestShutdownCmd)
+	addGuestSelectorFlags(guestShutdownCmd)
+	addGuestPowerFlags(guestShutdownCmd)
+	guestShutdownCmd.Flags().Bool("force", false, "power off guests that have not shut down when --timeout expires")
 	guestCmd.AddCommand(guestUpdateCmd)
 
 	guestCmd.AddCommand(guestBackupCmd)
@@ -539,6 +918,23 @@ func init() {
 
 	guestCmd.AddCommand(guestMigrateCmd)
 	guestMigrateCmd.Flags().String("hostid", "", "The host the guest will be migrated to")
+	guestMigrateCmd.Flags().Bool("wait", false, "wait for the migration to finish and show its progress")
+	guestMigrateCmd.Flags().Duration("timeout", 0, "maximum time to wait, 0 waits forever")
+
+	guestCmd.AddCommand(guestSnapshotCmd)
+	guestSnapshotCmd.AddCommand(guestSnapshotListCmd)
+	guestSnapshotCmd.AddCommand(guestSnapshotCreateCmd)
+	addTaskFlags(guestSnapshotCreateCmd)
+	guestSnapshotCmd.AddCommand(guestSnapshotRevertCmd)
+	addTaskFlags(guestSnapshotRevertCmd)
+	guestSnapshotCmd.AddCommand(guestSnapshotDeleteCmd)
+
+	guestCmd.AddCommand(guestConsoleCmd)
+	guestConsoleCmd.Flags().String("listen", "127.0.0.1", "local address to listen on")
+	guestConsoleCmd.Flags().Int("local-port", 0, "local port to listen on, 0 picks a free port")
+
+	guestCmd.AddCommand(guestScreenshotCmd)
+	guestScreenshotCmd.Flags().StringP("output", "o", "", "file to write the png to, - for stdout. Defaults to [Name].png")
 
 	guestCmd.AddCommand(guestAddExternalCmd)
 	guestCmd.AddCommand(guestUpdateExternalCmd)
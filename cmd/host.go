@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/hive-io/hive-go-client/rest"
 	"github.com/spf13/cobra"
@@ -264,6 +267,83 @@ var hostStateCmd = &cobra.Command{
 	},
 }
 
+var hostEvacuateCmd = &cobra.Command{
+	Use:   "evacuate {-i hostid | -n hostname | --ip ip_address | hostid}",
+	Short: "migrate all guests off a host and put it into maintenance",
+	Long: `Migrate every running guest off a host and put the host into maintenance once all guests have moved
+With --placement each guest is sent to the available host with the least guest memory allowed by its pool affinity,
+otherwise the server chooses the destination.
+If any migration fails the failed guests are reported and the host is left in its current state.
+--format accepts table in addition to json, yaml and json-compact for the results
+`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindHostIDFlags(cmd, args)
+		viper.BindPFlag("parallel", cmd.Flags().Lookup("parallel"))
+		viper.BindPFlag("placement", cmd.Flags().Lookup("placement"))
+		viper.BindPFlag("timeout", cmd.Flags().Lookup("timeout"))
+		viper.BindPFlag("maintenance", cmd.Flags().Lookup("maintenance"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		format := viper.GetString("format")
+		switch format {
+		case "json", "json-compact", "yaml", "table":
+		default:
+			fmt.Println("Error: Unsupported format")
+			os.Exit(1)
+		}
+		host, err := getHost(cmd, args)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		hostnames := map[string]string{}
+		if hosts, err := restClient.ListHosts(""); err == nil {
+			for _, h := range hosts {
+				hostnames[h.Hostid] = h.Hostname
+			}
+		}
+		options := rest.EvacuateOptions{
+			Parallel:    viper.GetInt("parallel"),
+			Placement:   viper.GetBool("placement"),
+			Timeout:     viper.GetDuration("timeout"),
+			Maintenance: viper.GetBool("maintenance"),
+			Progress: func(result rest.GuestResult) {
+				if result.Err != nil {
+					fmt.Fprintf(os.Stderr, "%s failed: %v\n", result.Guest.Name, result.Err)
+					return
+				}
+				fmt.Fprintf(os.Stderr, "%s migrated to %s\n", result.Guest.Name, hostnames[result.Guest.Hostid])
+			},
+		}
+		fmt.Fprintf(os.Stderr, "Evacuating %s\n", host.Hostname)
+		evacuated, err := host.Evacuate(ctx, restClient, options)
+		results := make([]guestResult, 0, len(evacuated))
+		for _, result := range evacuated {
+			if result.Err != nil {
+				results = append(results, guestResult{Name: result.Guest.Name, Result: "failed", Error: result.Err.Error()})
+				continue
+			}
+			destination := hostnames[result.Guest.Hostid]
+			if destination == "" {
+				destination = result.Guest.Hostid
+			}
+			results = append(results, guestResult{Name: result.Guest.Name, Result: "migrated to " + destination})
+		}
+		if len(evacuated) > 0 {
+			printGuestResults(results, format)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if options.Maintenance {
+			fmt.Fprintf(os.Stderr, "%s is in maintenance\n", host.Hostname)
+		}
+	},
+}
+
 var hostEnableGatewayCmd = &cobra.Command{
 	Use:    "enable-gateway-mode {-i hostid | -n hostname | --ip ip_address | hostid}",
 	Short:  "Convert the host into a gateway appliance",
@@ -665,6 +745,12 @@ func init() {
 	addHostIDFlags(hostEnableCRSCmd)
 	hostCmd.AddCommand(hostDisableCRSCmd)
 	addHostIDFlags(hostDisableCRSCmd)
+	hostCmd.AddCommand(hostEvacuateCmd)
+	addHostIDFlags(hostEvacuateCmd)
+	hostEvacuateCmd.Flags().Int("parallel", 2, "number of guests to migrate at once")
+	hostEvacuateCmd.Flags().Bool("placement", false, "place each guest on the least loaded host allowed by its pool")
+	hostEvacuateCmd.Flags().Duration("timeout", 0, "maximum time to wait for each migration, 0 waits forever")
+	hostEvacuateCmd.Flags().Bool("maintenance", true, "put the host into maintenance when all guests have been migrated")
 	hostCmd.AddCommand(hostEnableGatewayCmd)
 	addHostIDFlags(hostEnableGatewayCmd)
 	hostCmd.AddCommand(hostDisableGatewayCmd)
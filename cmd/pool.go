@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/hive-io/hive-go-client/rest"
@@ -316,6 +320,65 @@ var poolMergeCmd = &cobra.Command{
 	},
 }
 
+var poolWaitCmd = &cobra.Command{
+	Use:   "wait",
+	Short: "wait for the guests in a pool to reach a state",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlag("id", cmd.Flags().Lookup("id"))
+		viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+		viper.BindPFlag("state", cmd.Flags().Lookup("state"))
+		viper.BindPFlag("min", cmd.Flags().Lookup("min"))
+		viper.BindPFlag("timeout", cmd.Flags().Lookup("timeout"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var pool *rest.Pool
+		var err error
+		switch {
+		case cmd.Flags().Changed("id"):
+			pool, err = restClient.GetPool(viper.GetString("id"))
+		case cmd.Flags().Changed("name"):
+			pool, err = restClient.GetPoolByName(viper.GetString("name"))
+		default:
+			cmd.Usage()
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		last := ""
+		options := rest.PoolWaitOptions{
+			State:    viper.GetString("state"),
+			MinReady: viper.GetInt("min"),
+			Progress: func(status rest.PoolGuestStatus) {
+				progress := fmt.Sprintf("%s: %d/%d guests %s, %d failed", pool.Name, status.Ready, len(status.Guests), viper.GetString("state"), len(status.Failed))
+				if progress != last {
+					last = progress
+					fmt.Fprintln(os.Stderr, progress)
+				}
+			},
+		}
+		_, err = pool.WaitForGuests(ctx, restClient, viper.GetDuration("timeout"), options)
+		var guestsErr *rest.PoolGuestsError
+		if errors.As(err, &guestsErr) {
+			failed := []map[string]string{}
+			for _, guest := range guestsErr.Guests {
+				failed = append(failed, map[string]string{"name": guest.Name, "code": guest.Error.Code, "message": guest.Error.Message})
+			}
+			fmt.Println(formatString(failed))
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println(formatString("Pool Ready"))
+	},
+}
+
 func init() {
 	RootCmd.AddCommand(poolCmd)
 	poolCmd.AddCommand(poolCreateCmd)
@@ -353,4 +416,11 @@ func init() {
 	poolCmd.AddCommand(poolMergeCmd)
 	poolMergeCmd.Flags().StringP("id", "i", "", "pool pool Id")
 	poolMergeCmd.Flags().StringP("name", "n", "", "pool pool Name")
+
+	poolCmd.AddCommand(poolWaitCmd)
+	poolWaitCmd.Flags().StringP("id", "i", "", "pool Id")
+	poolWaitCmd.Flags().StringP("name", "n", "", "pool Name")
+	poolWaitCmd.Flags().String("state", "ready", "guest state to wait for")
+	poolWaitCmd.Flags().Int("min", 0, "minimum number of guests in the state, 0 waits for every guest")
+	poolWaitCmd.Flags().Duration("timeout", 0, "maximum time to wait, 0 waits forever")
 }
@@ -3,6 +3,7 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/url"
 	"os"
 	"runtime/debug"
@@ -64,8 +65,15 @@ func init() {
 	cobra.OnInitialize(initConfig)
 	RootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "", "", "config file")
 	RootCmd.PersistentFlags().BoolP("insecure", "k", false, "ignore certificate errors")
+	RootCmd.PersistentFlags().String("ca-file", "", "CA bundle used to verify the server certificate")
+	RootCmd.PersistentFlags().String("client-cert", "", "client certificate for mutual tls")
+	RootCmd.PersistentFlags().String("client-key", "", "client certificate key for mutual tls")
+	RootCmd.PersistentFlags().StringSlice("pin-sha256", []string{}, "sha256 fingerprints of trusted server certificates")
+	RootCmd.PersistentFlags().String("proxy", "", "http or socks5 proxy url")
+	RootCmd.PersistentFlags().Bool("debug", false, "log requests and responses to stderr")
 	RootCmd.PersistentFlags().String("host", "", "Hostname or ip address")
 	RootCmd.PersistentFlags().Uint("port", 8443, "port")
+	RootCmd.PersistentFlags().String("url", "", "Base url including scheme, host, port and path prefix. Overrides --host and --port")
 	RootCmd.PersistentFlags().StringP("user", "u", "admin", "Admin username")
 	RootCmd.PersistentFlags().StringP("password", "p", "", "Admin user password")
 	RootCmd.PersistentFlags().StringP("realm", "r", "local", "Admin user realm")
@@ -74,7 +82,14 @@ func init() {
 
 	viper.BindPFlag("host", RootCmd.PersistentFlags().Lookup("host"))
 	viper.BindPFlag("port", RootCmd.PersistentFlags().Lookup("port"))
+	viper.BindPFlag("url", RootCmd.PersistentFlags().Lookup("url"))
 	viper.BindPFlag("insecure", RootCmd.PersistentFlags().Lookup("insecure"))
+	viper.BindPFlag("ca-file", RootCmd.PersistentFlags().Lookup("ca-file"))
+	viper.BindPFlag("client-cert", RootCmd.PersistentFlags().Lookup("client-cert"))
+	viper.BindPFlag("client-key", RootCmd.PersistentFlags().Lookup("client-key"))
+	viper.BindPFlag("pin-sha256", RootCmd.PersistentFlags().Lookup("pin-sha256"))
+	viper.BindPFlag("proxy", RootCmd.PersistentFlags().Lookup("proxy"))
+	viper.BindPFlag("debug", RootCmd.PersistentFlags().Lookup("debug"))
 	viper.BindPFlag("user", RootCmd.PersistentFlags().Lookup("user"))
 	viper.BindPFlag("password", RootCmd.PersistentFlags().Lookup("password"))
 	viper.BindPFlag("realm", RootCmd.PersistentFlags().Lookup("realm"))
@@ -110,21 +125,44 @@ func initConfig() {
 
 func connectRest(cmd *cobra.Command, args []string) {
 	cmd.MarkPersistentFlagRequired("host")
-	if viper.GetString("host") == "" {
+	host := viper.GetString("host")
+	if baseURL := viper.GetString("url"); baseURL != "" {
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			fmt.Println("Error: Unable to parse url")
+			os.Exit(1)
+		}
+		host = u.Hostname()
+	}
+	if host == "" {
 		fmt.Println("Error: Host was not provided.")
 		cmd.Usage()
 		os.Exit(1)
 	}
 
 	restClient = &rest.Client{
-		Host:          viper.GetString("host"),
-		Port:          viper.GetUint("port"),
-		AllowInsecure: viper.GetBool("insecure"),
-		UserAgent:     "hioctl/" + version,
+		Host:               viper.GetString("host"),
+		Port:               viper.GetUint("port"),
+		BaseURL:            viper.GetString("url"),
+		AllowInsecure:      viper.GetBool("insecure"),
+		CAFile:             viper.GetString("ca-file"),
+		ClientCertFile:     viper.GetString("client-cert"),
+		ClientKeyFile:      viper.GetString("client-key"),
+		PinnedFingerprints: viper.GetStringSlice("pin-sha256"),
+		Proxy:              viper.GetString("proxy"),
+		RateLimit:          viper.GetFloat64("rate-limit"),
+		RateBurst:          viper.GetInt("rate-burst"),
+		MaxInFlight:        viper.GetInt("max-in-flight"),
+		UserAgent:          "hioctl/" + version,
+		KeepCredentials:    true,
+	}
+	if viper.GetBool("debug") {
+		restClient.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		restClient.LogBodies = true
 	}
 
 	password := viper.GetString("password")
-	if password == "" && restClient.Host != "localhost" {
+	if password == "" && host != "localhost" {
 		prompt := &survey.Password{
 			Message: "Password:",
 		}
@@ -1,9 +1,16 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/AlecAivazis/survey/v2"
 	"github.com/hive-io/hive-go-client/rest"
 	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
@@ -51,6 +58,38 @@ var taskForceCompleteCmd = &cobra.Command{
 	},
 }
 
+var taskCancelCmd = &cobra.Command{
+	Use:   "cancel",
+	Short: "cancel a running task",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlag("id", cmd.Flags().Lookup("id"))
+		viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var task *rest.Task
+		var err error
+		switch {
+		case cmd.Flags().Changed("id"):
+			task, err = restClient.GetTask(viper.GetString("id"))
+		case cmd.Flags().Changed("name"):
+			task, err = restClient.GetTaskByName(viper.GetString("name"))
+		default:
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = task.Cancel(restClient)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
 var taskGetCmd = &cobra.Command{
 	Use:   "get",
 	Short: "get task details",
@@ -83,16 +122,35 @@ var taskGetCmd = &cobra.Command{
 var taskListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "list tasks",
+	Long: `List tasks
+--format accepts table in addition to json, yaml and json-compact.
+Times are RFC3339 timestamps or durations before now such as 24h
+Tasks are sorted newest first unless --asc is set. --task-host, more than one --state and the time
+filters are applied by hioctl to every task, then --count and --offset select the results
+
+Example:
+hioctl task list --state failed --started-after 24h --sort duration --format table
+`,
 	PreRun: func(cmd *cobra.Command, args []string) {
 		bindListFlags(cmd)
+		for _, flag := range []string{"state", "type", "username", "task-host", "started-after", "started-before", "finished-after", "finished-before", "sort", "asc"} {
+			viper.BindPFlag(flag, cmd.Flags().Lookup(flag))
+		}
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		tasks, err := restClient.ListTasks(listFlagsToQuery())
+		query, err := taskListQuery()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		tasks, err := restClient.QueryTasks(query)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		if cmd.Flags().Changed("details") {
+		if viper.GetString("format") == "table" {
+			printTaskTable(tasks)
+		} else if cmd.Flags().Changed("details") {
 			fmt.Println(formatString(tasks))
 		} else {
 			list := []map[string]string{}
@@ -153,12 +211,26 @@ func init() {
 	taskForceCompleteCmd.Flags().StringP("id", "i", "", "task id")
 	taskForceCompleteCmd.Flags().StringP("name", "n", "", "task name")
 
+	taskCmd.AddCommand(taskCancelCmd)
+	taskCancelCmd.Flags().StringP("id", "i", "", "task id")
+	taskCancelCmd.Flags().StringP("name", "n", "", "task name")
+
 	taskCmd.AddCommand(taskGetCmd)
 	taskGetCmd.Flags().StringP("id", "i", "", "task id")
 	taskGetCmd.Flags().StringP("name", "n", "", "task name")
 
 	taskCmd.AddCommand(taskListCmd)
 	addListFlags(taskListCmd)
+	taskListCmd.Flags().StringSlice("state", []string{}, "only show tasks in these states")
+	taskListCmd.Flags().String("type", "", "only show tasks of this type")
+	taskListCmd.Flags().String("username", "", "only show tasks started by this user")
+	taskListCmd.Flags().String("task-host", "", "only show tasks running on this hostid")
+	taskListCmd.Flags().String("started-after", "", "only show tasks started after this time")
+	taskListCmd.Flags().String("started-before", "", "only show tasks started before this time")
+	taskListCmd.Flags().String("finished-after", "", "only show tasks finished after this time")
+	taskListCmd.Flags().String("finished-before", "", "only show tasks finished before this time")
+	taskListCmd.Flags().String("sort", "startTime", "sort by startTime, finishedTime, duration, name, state or type")
+	taskListCmd.Flags().Bool("asc", false, "sort in ascending order, the default is descending so the newest tasks come first")
 
 	taskCmd.AddCommand(taskWaitCmd)
 	taskWaitCmd.Flags().StringP("id", "i", "", "task id")
@@ -167,6 +239,69 @@ func init() {
 	taskWaitCmd.Flags().Bool("progress-bar", false, "print progress-bar")
 }
 
+// parseTaskTime parses an RFC3339 timestamp or a duration before now
+func parseTaskTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q, expected RFC3339 or a duration such as 24h", value)
+	}
+	return t, nil
+}
+
+// taskListQuery builds a TaskQuery from the task list flags
+func taskListQuery() (*rest.TaskQuery, error) {
+	var times [4]time.Time
+	for i, flag := range []string{"started-after", "started-before", "finished-after", "finished-before"} {
+		t, err := parseTaskTime(viper.GetString(flag))
+		if err != nil {
+			return nil, err
+		}
+		times[i] = t
+	}
+	filters, err := url.ParseQuery(listFlagsToQuery())
+	if err != nil {
+		return nil, err
+	}
+	query := rest.NewTaskQuery().
+		State(viper.GetStringSlice("state")...).
+		Type(viper.GetString("type")).
+		Username(viper.GetString("username")).
+		Host(viper.GetString("task-host")).
+		StartedBetween(times[0], times[1]).
+		FinishedBetween(times[2], times[3]).
+		SortBy(rest.TaskSortField(viper.GetString("sort")), !viper.GetBool("asc")).
+		Limit(viper.GetInt("count"), viper.GetInt("offset"))
+	for key, values := range filters {
+		if key == "count" || key == "offset" {
+			continue
+		}
+		for _, value := range values {
+			query.Filter(key, value)
+		}
+	}
+	return query, nil
+}
+
+func printTaskTable(tasks []rest.Task) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tTYPE\tSTATE\tUSER\tHOST\tSTARTED\tDURATION\tMESSAGE")
+	for _, task := range tasks {
+		started := ""
+		if !task.StartTime.IsZero() {
+			started = task.StartTime.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", task.ID, task.Name, task.Type, task.State, task.Username,
+			task.Ref.Host, started, task.Duration().Round(time.Second), strings.ReplaceAll(task.Message, "\n", " "))
+	}
+	w.Flush()
+}
+
 func addTaskFlags(cmd *cobra.Command) {
 	cmd.Flags().Bool("wait", false, "wait for task to complete")
 	cmd.Flags().Bool("raw-progress", false, "print progress as a number with --wait")
@@ -188,7 +323,16 @@ func handleTask(task *rest.Task, err error) {
 		os.Exit(1)
 	}
 	if viper.GetBool("wait") {
-		if err := waitForTask(task, viper.GetBool("raw-progress"), viper.GetBool("progress-bar")); err != nil {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		err := waitForTaskWithContext(ctx, task, viper.GetBool("raw-progress"), viper.GetBool("progress-bar"))
+		interrupted := ctx.Err() != nil
+		stop()
+		if interrupted {
+			fmt.Println("")
+			offerCancelTask(task)
+			os.Exit(1)
+		}
+		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
@@ -201,14 +345,44 @@ func handleTask(task *rest.Task, err error) {
 
 }
 
+// offerCancelTask asks whether a task that is still running on the server should be cancelled after the wait was interrupted
+func offerCancelTask(task *rest.Task) {
+	current, err := restClient.GetTask(task.ID)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if current.State == "completed" || current.State == "failed" || !current.Cancellable {
+		fmt.Printf("Stopped waiting, task %s is still %s on the server\n", current.ID, current.State)
+		return
+	}
+	cancel := false
+	prompt := &survey.Confirm{
+		Message: fmt.Sprintf("Cancel task %s (%s) on the server?", current.ID, current.Description),
+	}
+	if err := survey.AskOne(prompt, &cancel); err != nil || !cancel {
+		fmt.Printf("Stopped waiting, task %s is still running on the server\n", current.ID)
+		return
+	}
+	if err := current.Cancel(restClient); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(formatString("Task Cancelled"))
+}
+
 func waitForTask(task *rest.Task, rawProgress, progressBar bool) error {
+	return waitForTaskWithContext(context.Background(), task, rawProgress, progressBar)
+}
+
+func waitForTaskWithContext(ctx context.Context, task *rest.Task, rawProgress, progressBar bool) error {
 	if task == nil {
 		return fmt.Errorf("error reading task")
 	}
 	if progressBar {
-		taskProgressBar(task)
+		return taskProgressBar(ctx, task)
 	} else {
-		taskVal, err := task.WaitForTask(restClient, rawProgress)
+		taskVal, err := task.WaitForTaskWithContext(ctx, restClient, rawProgress)
 		if err != nil {
 			return err
 		}
@@ -219,7 +393,7 @@ func waitForTask(task *rest.Task, rawProgress, progressBar bool) error {
 	return nil
 }
 
-func taskProgressBar(task *rest.Task) error {
+func taskProgressBar(ctx context.Context, task *rest.Task) error {
 	bar := progressbar.NewOptions(100,
 		progressbar.OptionFullWidth(),
 		progressbar.OptionSetPredictTime(false),
@@ -228,9 +402,13 @@ func taskProgressBar(task *rest.Task) error {
 	taskData := make(chan rest.Task)
 	var newVal rest.Task
 	bar.Set(int(task.Progress))
-	go task.WatchTask(restClient, taskData, errChannel)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go task.WatchTaskWithContext(ctx, restClient, taskData, errChannel)
 	for {
 		select {
+		case <-ctx.Done():
+			return ctx.Err()
 		case newVal = <-taskData:
 			bar.Set(int(newVal.Progress))
 			if newVal.State == "completed" {
@@ -15,7 +15,9 @@ var utilCmd = &cobra.Command{
 		cmd.Usage()
 		os.Exit(0)
 	},
-	Hidden: true,
+	// the utilities only generate files and do not connect to a server
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	Hidden:           true,
 }
 
 var bashCompletionCmd = &cobra.Command{
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hive-io/hive-go-client/rest"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// watchTables maps the watch command arguments to change feed table names
+var watchTables = map[string]string{
+	"guest":   "guest",
+	"pool":    "pool",
+	"task":    "task",
+	"host":    "host",
+	"alert":   "alert",
+	"storage": "storagePool",
+}
+
+// watchRecord is a record from any table decoded without a schema
+type watchRecord = map[string]interface{}
+
+// fieldChange is the old and new value of a field shown with --diff
+type fieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// watchEvent is printed for every change
+type watchEvent struct {
+	Type    rest.ChangeType        `json:"type"`
+	Time    time.Time              `json:"time"`
+	ID      string                 `json:"id,omitempty"`
+	Name    string                 `json:"name,omitempty"`
+	OldVal  watchRecord            `json:"old_val,omitempty"`
+	NewVal  watchRecord            `json:"new_val,omitempty"`
+	Changes map[string]fieldChange `json:"changes,omitempty"`
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [guest|pool|task|host|alert|storage]",
+	Short: "stream live changes for a table",
+	Long: `Stream live changes for a table until interrupted
+--format accepts table in addition to json, yaml and json-compact
+
+Example:
+hioctl watch guest --filter poolId=0a1b2c --diff --format table
+`,
+	ValidArgs: []string{"guest", "pool", "task", "host", "alert", "storage"},
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlag("filter", cmd.Flags().Lookup("filter"))
+		viper.BindPFlag("initial", cmd.Flags().Lookup("initial"))
+		viper.BindPFlag("diff", cmd.Flags().Lookup("diff"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		filter, err := parseWatchFilter(viper.GetStringSlice("filter"))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		format := viper.GetString("format")
+		switch format {
+		case "json", "json-compact", "yaml", "table":
+		default:
+			fmt.Println("Error: Unsupported format")
+			os.Exit(1)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		options := rest.ChangeFeedOptions{
+			Table:              watchTables[args[0]],
+			Filter:             filter,
+			IncludeInitial:     viper.GetBool("initial"),
+			Reconnect:          true,
+			RefetchOnReconnect: viper.GetBool("initial"),
+		}
+		feed, err := rest.Watch[watchRecord](ctx, restClient, options)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer feed.Close()
+
+		if format == "table" {
+			fmt.Printf("%-20s %-12s %-36s %-24s %s\n", "TIME", "CHANGE", "ID", "NAME", "DETAILS")
+		}
+		errs := feed.Errors
+		for {
+			select {
+			case change, ok := <-feed.Changes:
+				if !ok {
+					if ctx.Err() != nil {
+						return
+					}
+					// the error that stopped the feed may still be unread
+					if errs != nil {
+						for err := range errs {
+							fmt.Fprintln(os.Stderr, err)
+						}
+					}
+					fmt.Fprintln(os.Stderr, "Error: change feed closed")
+					os.Exit(1)
+				}
+				printWatchEvent(newWatchEvent(change, viper.GetBool("diff")), format)
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+					continue
+				}
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().StringSlice("filter", []string{}, "only show records where key=value, may be repeated")
+	watchCmd.Flags().Bool("initial", false, "show the current records before streaming changes")
+	watchCmd.Flags().Bool("diff", false, "only show the fields that changed")
+}
+
+func parseWatchFilter(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	filter := map[string]string{}
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid filter %q, expected key=value", pair)
+		}
+		filter[key] = value
+	}
+	return filter, nil
+}
+
+// firstString returns the first of keys that is a non-empty string in obj
+func firstString(obj watchRecord, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := obj[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func newWatchEvent(change rest.Change[watchRecord], diff bool) watchEvent {
+	event := watchEvent{Type: change.Type, Time: time.Now()}
+	var oldVal, newVal watchRecord
+	if change.Old != nil {
+		oldVal = *change.Old
+	}
+	if change.New != nil {
+		newVal = *change.New
+	}
+	for _, obj := range []watchRecord{newVal, oldVal} {
+		if event.ID == "" {
+			event.ID = firstString(obj, "id", "hostid", "name")
+		}
+		if event.Name == "" {
+			event.Name = firstString(obj, "name", "hostname", "message")
+		}
+	}
+	if diff {
+		if change.Type != rest.ChangeReconnected {
+			event.Changes = diffRecords(oldVal, newVal)
+		}
+	} else {
+		event.OldVal = oldVal
+		event.NewVal = newVal
+	}
+	return event
+}
+
+// diffRecords returns the fields that differ between old and new. Nested objects are compared field by field
+// and reported with dotted keys
+func diffRecords(oldVal, newVal watchRecord) map[string]fieldChange {
+	changes := map[string]fieldChange{}
+	var walk func(prefix string, a, b watchRecord)
+	walk = func(prefix string, a, b watchRecord) {
+		keys := map[string]bool{}
+		for key := range a {
+			keys[key] = true
+		}
+		for key := range b {
+			keys[key] = true
+		}
+		for key := range keys {
+			oldField, newField := a[key], b[key]
+			if reflect.DeepEqual(oldField, newField) {
+				continue
+			}
+			oldObj, oldIsObj := oldField.(watchRecord)
+			newObj, newIsObj := newField.(watchRecord)
+			if oldIsObj && newIsObj {
+				walk(prefix+key+".", oldObj, newObj)
+				continue
+			}
+			changes[prefix+key] = fieldChange{Old: oldField, New: newField}
+		}
+	}
+	walk("", oldVal, newVal)
+	return changes
+}
+
+func printWatchEvent(event watchEvent, format string) {
+	switch format {
+	case "table":
+		fmt.Printf("%-20s %-12s %-36s %-24s %s\n", event.Time.Format("2006-01-02 15:04:05"), event.Type, event.ID, event.Name, watchDetails(event))
+	case "yaml":
+		fmt.Print("---\n" + formatString(event))
+	default:
+		fmt.Println(formatString(event))
+	}
+}
+
+// watchDetails summarizes an event for the table format
+func watchDetails(event watchEvent) string {
+	if event.Changes != nil {
+		keys := make([]string, 0, len(event.Changes))
+		for key := range event.Changes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		details := make([]string, 0, len(keys))
+		for _, key := range keys {
+			change := event.Changes[key]
+			details = append(details, fmt.Sprintf("%s: %v -> %v", key, change.Old, change.New))
+		}
+		return strings.Join(details, ", ")
+	}
+	obj := event.NewVal
+	if obj == nil {
+		obj = event.OldVal
+	}
+	if state := firstString(obj, "state"); state != "" {
+		return "state: " + state
+	}
+	return ""
+}
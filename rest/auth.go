@@ -0,0 +1,79 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Credentials contains the username, password, and realm used to log in
+type Credentials struct {
+	Username string
+	Password string
+	Realm    string
+}
+
+// CredentialProvider returns the credentials used to log in again when the token expires
+type CredentialProvider func(ctx context.Context) (Credentials, error)
+
+// StaticCredentials returns a CredentialProvider that always returns the same credentials
+func StaticCredentials(username, password, realm string) CredentialProvider {
+	return func(ctx context.Context) (Credentials, error) {
+		return Credentials{Username: username, Password: password, Realm: realm}, nil
+	}
+}
+
+// authPaths are never replayed after a 401 to avoid recursive logins
+var authPaths = map[string]bool{"auth": true, "authBrokerUser": true}
+
+func (client *Client) getToken() string {
+	client.tokenMu.RLock()
+	defer client.tokenMu.RUnlock()
+	return client.token
+}
+
+func (client *Client) setToken(token string) {
+	client.tokenMu.Lock()
+	defer client.tokenMu.Unlock()
+	client.token = token
+}
+
+// canReauthenticate returns true if the client has credentials to log in again
+func (client *Client) canReauthenticate(path string) bool {
+	return client.CredentialProvider != nil && !authPaths[path]
+}
+
+// reauthenticate logs in again if the token has not already been replaced since staleToken was used
+func (client *Client) reauthenticate(ctx context.Context, staleToken string) error {
+	client.loginMu.Lock()
+	defer client.loginMu.Unlock()
+	if client.getToken() != staleToken {
+		return nil
+	}
+	if client.CredentialProvider == nil {
+		return errors.New("no credentials available to refresh the token")
+	}
+	creds, err := client.CredentialProvider(ctx)
+	if err != nil {
+		return err
+	}
+	return client.login(ctx, creds)
+}
+
+// canReplay returns true if req can be sent again after logging in
+func (client *Client) canReplay(req *http.Request, path string) bool {
+	return client.canReauthenticate(path) && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+}
+
+// replay resends req with the current token
+func (client *Client) replay(httpClient *http.Client, req *http.Request) (*http.Response, error) {
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = body
+	}
+	req.Header.Set("Authorization", "Bearer "+client.getToken())
+	return client.doWithRetry(httpClient, req)
+}
@@ -97,7 +97,7 @@ func (client *Client) BrokerLogin(username, password, realm, token, mfaToken str
 	if err != nil {
 		return err
 	}
-	client.token = resp.Token
+	client.setToken(resp.Token)
 	return nil
 }
 
@@ -0,0 +1,129 @@
+package rest
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+// GuestSelector selects the guests for a bulk operation. Empty fields match every guest
+type GuestSelector struct {
+	PoolID string
+	Hostid string
+	// Tags lists tags a guest must all have
+	Tags []string
+	// States lists guest states a guest must be in one of
+	States []string
+	// Name is a glob matched against the guest name, for example "win10-*"
+	Name string
+}
+
+// Empty returns true if the selector matches every guest
+func (selector GuestSelector) Empty() bool {
+	return selector.PoolID == "" && selector.Hostid == "" && len(selector.Tags) == 0 && len(selector.States) == 0 && selector.Name == ""
+}
+
+// Match returns true if guest is selected
+func (selector GuestSelector) Match(guest Guest) bool {
+	if selector.PoolID != "" && guest.PoolID != selector.PoolID {
+		return false
+	}
+	if selector.Hostid != "" && guest.Hostid != selector.Hostid {
+		return false
+	}
+	for _, tag := range selector.Tags {
+		found := false
+		for _, guestTag := range guest.Tags {
+			if guestTag == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(selector.States) > 0 {
+		found := false
+		for _, state := range selector.States {
+			if strings.EqualFold(guest.GuestState, state) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if selector.Name != "" {
+		if ok, err := path.Match(selector.Name, guest.Name); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// SelectGuests returns the guests matching selector
+func (client *Client) SelectGuests(selector GuestSelector) ([]Guest, error) {
+	query := url.Values{}
+	if selector.PoolID != "" {
+		query.Set("poolId", selector.PoolID)
+	}
+	if selector.Hostid != "" {
+		query.Set("hostid", selector.Hostid)
+	}
+	guests, err := client.ListGuests(query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	selected := []Guest{}
+	for _, guest := range guests {
+		if selector.Match(guest) {
+			selected = append(selected, guest)
+		}
+	}
+	return selected, nil
+}
+
+// GuestResult is the outcome of a bulk operation for one guest
+type GuestResult struct {
+	Guest Guest
+	Err   error
+}
+
+// BulkGuestOperation runs operation for every guest with at most parallel operations running at once.
+// A failing guest does not stop the others. Guests that have not started when ctx is done fail with ctx.Err().
+// The results are in the same order as guests.
+func (client *Client) BulkGuestOperation(ctx context.Context, guests []Guest, parallel int, operation func(context.Context, *Guest) error) []GuestResult {
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]GuestResult, len(guests))
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i := range guests {
+		results[i].Guest = guests[i]
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+		// a free slot and ctx being done can be ready at once
+		if err := ctx.Err(); err != nil {
+			<-slots
+			results[i].Err = err
+			continue
+		}
+		wg.Add(1)
+		go func(result *GuestResult) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			result.Err = operation(ctx, &result.Guest)
+		}(&results[i])
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGuestSelectorMatch(t *testing.T) {
+	guest := Guest{Name: "win10-3", PoolID: "p1", Hostid: "h1", GuestState: "Ready", Tags: []string{"lab", "gpu"}}
+	tests := []struct {
+		name     string
+		selector GuestSelector
+		want     bool
+	}{
+		{name: "empty", selector: GuestSelector{}, want: true},
+		{name: "glob", selector: GuestSelector{Name: "win10-*"}, want: true},
+		{name: "glob single character", selector: GuestSelector{Name: "win10-?"}, want: true},
+		{name: "glob class", selector: GuestSelector{Name: "win10-[12]"}, want: false},
+		{name: "glob other prefix", selector: GuestSelector{Name: "win11-*"}, want: false},
+		{name: "invalid glob", selector: GuestSelector{Name: "win10-["}, want: false},
+		{name: "exact name", selector: GuestSelector{Name: "win10-3"}, want: true},
+		{name: "tag", selector: GuestSelector{Tags: []string{"lab"}}, want: true},
+		{name: "every tag", selector: GuestSelector{Tags: []string{"lab", "gpu"}}, want: true},
+		{name: "missing tag", selector: GuestSelector{Tags: []string{"lab", "prod"}}, want: false},
+		{name: "state ignores case", selector: GuestSelector{States: []string{"ready"}}, want: true},
+		{name: "any state", selector: GuestSelector{States: []string{"stopped", "ready"}}, want: true},
+		{name: "other state", selector: GuestSelector{States: []string{"stopped"}}, want: false},
+		{name: "pool", selector: GuestSelector{PoolID: "p1"}, want: true},
+		{name: "other pool", selector: GuestSelector{PoolID: "p2"}, want: false},
+		{name: "other host", selector: GuestSelector{Hostid: "h2"}, want: false},
+		{name: "all fields", selector: GuestSelector{Name: "win10-*", PoolID: "p1", Hostid: "h1", Tags: []string{"gpu"}, States: []string{"ready"}}, want: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.selector.Match(guest); got != test.want {
+				t.Fatalf("Match returned %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBulkGuestOperationLimitsConcurrency(t *testing.T) {
+	guests := make([]Guest, 20)
+	for i := range guests {
+		guests[i].Name = fmt.Sprintf("guest%d", i)
+	}
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	client := &Client{}
+	client.BulkGuestOperation(context.Background(), guests, 3, func(ctx context.Context, guest *Guest) error {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+	if maxRunning != 3 {
+		t.Fatalf("%d operations ran at once, want 3", maxRunning)
+	}
+}
+
+func TestBulkGuestOperationResultOrder(t *testing.T) {
+	guests := make([]Guest, 10)
+	for i := range guests {
+		guests[i].Name = fmt.Sprintf("guest%d", i)
+	}
+	client := &Client{}
+	results := client.BulkGuestOperation(context.Background(), guests, 4, func(ctx context.Context, guest *Guest) error {
+		var i int
+		fmt.Sscanf(guest.Name, "guest%d", &i)
+		// later guests finish first
+		time.Sleep(time.Duration(len(guests)-i) * time.Millisecond)
+		if i%3 == 0 {
+			return errors.New(guest.Name + " failed")
+		}
+		return nil
+	})
+	if len(results) != len(guests) {
+		t.Fatalf("got %d results, want %d", len(results), len(guests))
+	}
+	for i, result := range results {
+		if result.Guest.Name != guests[i].Name {
+			t.Fatalf("result %d is for %s, want %s", i, result.Guest.Name, guests[i].Name)
+		}
+		if failed := result.Err != nil; failed != (i%3 == 0) {
+			t.Fatalf("result %d error %v", i, result.Err)
+		}
+	}
+}
+
+func TestBulkGuestOperationCancelled(t *testing.T) {
+	guests := []Guest{{Name: "guest0"}, {Name: "guest1"}, {Name: "guest2"}}
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &Client{}
+	results := client.BulkGuestOperation(ctx, guests, 1, func(ctx context.Context, guest *Guest) error {
+		cancel()
+		return nil
+	})
+	if results[0].Err != nil {
+		t.Fatalf("the started guest failed with %v", results[0].Err)
+	}
+	for _, result := range results[1:] {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Fatalf("%s got %v, want context.Canceled", result.Guest.Name, result.Err)
+		}
+	}
+}
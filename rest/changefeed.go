@@ -0,0 +1,478 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ChangeFeed wrapper around a websocket to monitor database changes
+// Done is closed when the feed stops because of an error, Close, or the context being cancelled.
+// Messages are never sent on Data after Done is closed.
+type ChangeFeed struct {
+	Data      chan ChangeFeedMessage
+	Done      chan struct{}
+	client    *Client
+	options   ChangeFeedOptions
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	conn      *socketConn
+	closeOnce sync.Once
+	closeErr  error
+
+	// feeds created by FeedSession.Subscribe receive messages through queue
+	session *FeedSession
+	notify  chan struct{}
+	queue   []ChangeFeedMessage
+	final   bool
+	initial bool
+}
+
+// ChangeFeedMessage contains a change from the database
+// Initial is set for the current values sent when the feed is registered with includeInitial.
+// Reconnected is set on an empty message sent after the feed reconnected. Changes made while
+// the feed was disconnected may have been missed.
+type ChangeFeedMessage struct {
+	OldValue    json.RawMessage `json:"old_val"`
+	NewValue    json.RawMessage `json:"new_val"`
+	Error       error           `json:"-"`
+	Initial     bool            `json:"-"`
+	Reconnected bool            `json:"-"`
+}
+
+// ChangeFeedOptions configures a change feed created with GetChangeFeedWithOptions
+type ChangeFeedOptions struct {
+	Table          string
+	Filter         map[string]string
+	IncludeInitial bool
+	// Reconnect dials the feed again with backoff when the websocket fails
+	Reconnect bool
+	// RefetchOnReconnect requests the current value of every matching record after reconnecting
+	RefetchOnReconnect bool
+	// MaxReconnectAttempts limits consecutive failed reconnect attempts, 0 retries until the context is done
+	MaxReconnectAttempts int
+	// MinBackoff and MaxBackoff bound the delay between reconnect attempts. Defaults are 1s and 30s
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+func (feed *ChangeFeed) getConn() *socketConn {
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+	return feed.conn
+}
+
+// parseChangeFeedPacket decodes a change from a socket.io event and also returns the table the change belongs
+// to when the server includes it in the event name or arguments. ok is false for packets that do not contain a change
+func parseChangeFeedPacket(packet socketPacket) (msg ChangeFeedMessage, table string, ok bool) {
+	if packet.Type != socketEvent {
+		return msg, "", false
+	}
+
+	var jsonMsg []json.RawMessage
+	err := json.Unmarshal(packet.Data, &jsonMsg)
+	if err != nil {
+		msg.Error = err
+		return msg, "", true
+	}
+	if len(jsonMsg) < 3 {
+		msg.Error = fmt.Errorf("invalid changefeed message")
+		return msg, "", true
+	}
+	table = changeFeedTable(jsonMsg[0], jsonMsg[1])
+	if strings.Contains(string(jsonMsg[0]), "initial") {
+		msg.Initial = true
+		var newValue []json.RawMessage
+		err = json.Unmarshal(jsonMsg[2], &newValue)
+		if err != nil {
+			msg.Error = err
+		} else if len(newValue) > 0 {
+			msg.NewValue = newValue[0]
+		}
+	} else {
+		err = json.Unmarshal(jsonMsg[2], &msg)
+		if err != nil {
+			msg.Error = err
+		}
+	}
+	return msg, table, true
+}
+
+// changeFeedTable looks for the table name in the query argument, either as a string or an object with a
+// table field, falling back to the last segment of the event name such as "query:change:guest"
+func changeFeedTable(event, query json.RawMessage) string {
+	var table string
+	if json.Unmarshal(query, &table) == nil && table != "" {
+		return table
+	}
+	var options struct {
+		Table string `json:"table"`
+	}
+	if json.Unmarshal(query, &options) == nil && options.Table != "" {
+		return options.Table
+	}
+	var name string
+	if json.Unmarshal(event, &name) != nil {
+		return ""
+	}
+	segments := strings.Split(name, ":")
+	if last := segments[len(segments)-1]; last != "query" && last != "change" && last != "initial" {
+		return last
+	}
+	return ""
+}
+
+// send delivers msg to the consumer unless the feed is stopped first
+func (feed *ChangeFeed) send(msg ChangeFeedMessage) bool {
+	select {
+	case feed.Data <- msg:
+		return true
+	case <-feed.ctx.Done():
+		return false
+	}
+}
+
+// enqueue adds msg to the messages waiting to be forwarded to Data without blocking the session reader.
+// When final is set the feed stops after delivering msg
+func (feed *ChangeFeed) enqueue(msg ChangeFeedMessage, final bool) {
+	feed.mu.Lock()
+	if !feed.final {
+		feed.queue = append(feed.queue, msg)
+		feed.final = final
+	}
+	feed.mu.Unlock()
+	select {
+	case feed.notify <- struct{}{}:
+	default:
+	}
+}
+
+// forward delivers the queued messages of a session feed to Data in order
+func (feed *ChangeFeed) forward() {
+	defer feed.wg.Done()
+	defer close(feed.Done)
+	defer feed.cancel()
+	for {
+		feed.mu.Lock()
+		if len(feed.queue) == 0 {
+			final := feed.final
+			feed.mu.Unlock()
+			if final {
+				return
+			}
+			select {
+			case <-feed.notify:
+				continue
+			case <-feed.ctx.Done():
+				return
+			}
+		}
+		msg := feed.queue[0]
+		feed.queue[0] = ChangeFeedMessage{}
+		feed.queue = feed.queue[1:]
+		feed.mu.Unlock()
+		if !feed.send(msg) {
+			return
+		}
+	}
+}
+
+// closeConn closes the current websocket which unblocks a pending ReadMessage
+func (feed *ChangeFeed) closeConn() {
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+	feed.conn.Close()
+}
+
+func (feed *ChangeFeed) monitorChangeFeed() {
+	defer feed.wg.Done()
+	defer close(feed.Done)
+	defer feed.cancel()
+	for {
+		packet, err := feed.getConn().readPacket()
+		if feed.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			if !feed.options.Reconnect {
+				feed.send(ChangeFeedMessage{Error: err})
+				return
+			}
+			if err = feed.reconnect(err); err != nil {
+				if feed.ctx.Err() == nil {
+					feed.send(ChangeFeedMessage{Error: err})
+				}
+				return
+			}
+			if !feed.send(ChangeFeedMessage{Reconnected: true}) {
+				return
+			}
+			continue
+		}
+		if msg, _, ok := parseChangeFeedPacket(packet); ok && !feed.send(msg) {
+			return
+		}
+	}
+}
+
+// reconnect dials the feed again with exponential backoff until it succeeds or the attempts are exhausted
+func (feed *ChangeFeed) reconnect(cause error) error {
+	ctx := feed.ctx
+	includeInitial := feed.options.IncludeInitial || feed.options.RefetchOnReconnect
+	attrs := []slog.Attr{slog.String("table", feed.options.Table)}
+	return feed.client.redial(ctx, cause, feed.options.MaxReconnectAttempts, feed.options.MinBackoff, feed.options.MaxBackoff, attrs, func() error {
+		conn, err := feed.client.dialChangeFeedConn(ctx, feed.options.Table, feed.options.Filter, includeInitial)
+		if err != nil {
+			return err
+		}
+		feed.mu.Lock()
+		defer feed.mu.Unlock()
+		if ctx.Err() != nil {
+			conn.Close()
+			return ctx.Err()
+		}
+		feed.conn.Close()
+		feed.conn = conn
+		return nil
+	})
+}
+
+// redial calls dial with jittered exponential backoff until it succeeds, ctx is done or maxAttempts
+// consecutive attempts have failed. Zero backoffs default to 1s and 30s
+func (client *Client) redial(ctx context.Context, cause error, maxAttempts int, minBackoff, maxBackoff time.Duration, attrs []slog.Attr, dial func() error) error {
+	if minBackoff <= 0 {
+		minBackoff = time.Second
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = 30 * time.Second
+	}
+	delay := minBackoff
+	for attempt := 1; ; attempt++ {
+		if client.logEnabled(ctx) {
+			client.Logger.LogAttrs(ctx, slog.LevelDebug, "change feed reconnecting",
+				append(attrs, slog.Int("attempt", attempt), slog.String("cause", cause.Error()))...)
+		}
+		timer := time.NewTimer(delay/2 + rand.N(delay/2+1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		err := dial()
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		cause = err
+		if maxAttempts > 0 && attempt >= maxAttempts {
+			return err
+		}
+		delay = min(delay*2, maxBackoff)
+	}
+}
+
+// changeFeedKeepAlive pings the server and closes the websocket when the feed is stopped
+func (feed *ChangeFeed) changeFeedKeepAlive() {
+	defer feed.wg.Done()
+	ticker := time.NewTicker(feed.getConn().pingInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-feed.ctx.Done():
+			feed.closeConn()
+			return
+		case <-ticker.C:
+			feed.getConn().ping()
+		}
+	}
+}
+
+// Close disconnects the changefeed websocket and waits for its goroutines to exit.
+// It is safe to call Close more than once and without reading the remaining messages from Data.
+func (feed *ChangeFeed) Close() error {
+	feed.closeOnce.Do(func() {
+		if feed.session != nil {
+			feed.closeErr = feed.session.unsubscribe(feed)
+			feed.cancel()
+			feed.wg.Wait()
+			return
+		}
+		if feed.ctx.Err() == nil {
+			deadline := time.Now().Add(time.Second)
+			feed.closeErr = feed.getConn().closeGracefully(deadline)
+		}
+		feed.cancel()
+		feed.wg.Wait()
+	})
+	return feed.closeErr
+}
+
+// GetChangeFeed returns a ChangeFeed for monitoring the specified table
+// filter can be used to limit the changes monitored.
+// example to monitor a single task:
+// client.GetChangeFeed("task", map[string]string{"id": task.ID})
+func (client *Client) GetChangeFeed(table string, filter map[string]string, includeInitial bool) (*ChangeFeed, error) {
+	return client.GetChangeFeedWithContext(client.getContext(), table, filter, includeInitial)
+}
+
+// GetChangeFeedWithContext returns a ChangeFeed for monitoring the specified table with a custom context
+// filter can be used to limit the changes monitored.
+// example to monitor a single task:
+// client.GetChangeFeed("task", map[string]string{"id": task.ID})
+func (client *Client) GetChangeFeedWithContext(ctx context.Context, table string, filter map[string]string, includeInitial bool) (*ChangeFeed, error) {
+	return client.GetChangeFeedWithOptions(ctx, ChangeFeedOptions{Table: table, Filter: filter, IncludeInitial: includeInitial})
+}
+
+// GetChangeFeedWithOptions returns a ChangeFeed configured by options.
+// example to monitor a single guest and reconnect if the connection is lost:
+// client.GetChangeFeedWithOptions(ctx, ChangeFeedOptions{Table: "guest", Filter: map[string]string{"name": name}, Reconnect: true, RefetchOnReconnect: true})
+func (client *Client) GetChangeFeedWithOptions(ctx context.Context, options ChangeFeedOptions) (*ChangeFeed, error) {
+	c, err := client.dialChangeFeedConn(ctx, options.Table, options.Filter, options.IncludeInitial)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	incomingData := make(chan ChangeFeedMessage)
+	feedCtx, cancel := context.WithCancel(ctx)
+	feed := &ChangeFeed{Data: incomingData, Done: done, client: client, options: options, ctx: feedCtx, cancel: cancel, conn: c}
+
+	feed.wg.Add(2)
+	go feed.changeFeedKeepAlive()
+	go feed.monitorChangeFeed()
+
+	return feed, nil
+}
+
+// dialChangeFeedConn connects to the socket.io endpoint and registers a change feed for table
+func (client *Client) dialChangeFeedConn(ctx context.Context, table string, filter map[string]string, includeInitial bool) (*socketConn, error) {
+	c, err := client.dialChangeFeedSocket(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = registerChangeFeed(c, "query:change:register", table, filter, includeInitial)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// registerChangeFeed emits the event registering or unregistering a change feed
+func registerChangeFeed(c *socketConn, event, table string, filter map[string]string, includeInitial bool) error {
+	options := map[string]interface{}{"table": table, "includeInitial": includeInitial, "filter": filter}
+	return c.emit(event, options)
+}
+
+// dialChangeFeedSocket connects to the socket.io endpoint and completes the engine.io handshake
+func (client *Client) dialChangeFeedSocket(ctx context.Context) (*socketConn, error) {
+	query := url.Values{"transport": {"websocket"}, "EIO": {strconv.Itoa(engineIOVersion)}}
+	c, err := client.dialWebsocket(ctx, "/socket.io/", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { c.Close() })
+	defer stop()
+	conn, err := newSocketConn(c, 20*time.Second)
+	if err != nil {
+		c.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// dialWebsocket opens a websocket to path below the base url, logging in again if the token has expired
+func (client *Client) dialWebsocket(ctx context.Context, path string, query url.Values, header http.Header) (*websocket.Conn, error) {
+	tlsConfig, err := client.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := client.proxyFunc()
+	if err != nil {
+		return nil, err
+	}
+	dialer := websocket.Dialer{
+		Proxy:            proxy,
+		TLSClientConfig:  tlsConfig,
+		HandshakeTimeout: 20 * time.Second,
+	}
+
+	token := client.getToken()
+	wsURL, err := client.websocketURL(path, query, token)
+	if err != nil {
+		return nil, err
+	}
+	c, res, err := client.dialWebsocketURL(ctx, &dialer, wsURL, header)
+	if err != nil && res != nil && res.StatusCode == http.StatusUnauthorized && client.canReauthenticate(strings.Trim(path, "/")) {
+		if err = client.reauthenticate(ctx, token); err == nil {
+			if wsURL, err = client.websocketURL(path, query, client.getToken()); err == nil {
+				c, _, err = client.dialWebsocketURL(ctx, &dialer, wsURL, header)
+			}
+		}
+	}
+	return c, err
+}
+
+// dialWebsocketURL opens a websocket, respecting the client rate limits
+func (client *Client) dialWebsocketURL(ctx context.Context, dialer *websocket.Dialer, wsURL string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	if err := client.waitRateLimit(ctx); err != nil {
+		return nil, nil, err
+	}
+	release, err := client.acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+	start := time.Now()
+	c, res, err := dialer.DialContext(ctx, wsURL, header)
+	if client.logEnabled(ctx) {
+		u, _ := url.Parse(wsURL)
+		attrs := []slog.Attr{slog.String("path", redactURL(u)), slog.Duration("latency", time.Since(start))}
+		if res != nil {
+			attrs = append(attrs, slog.Int("status", res.StatusCode))
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		client.Logger.LogAttrs(ctx, slog.LevelDebug, "websocket connect", attrs...)
+	}
+	return c, res, err
+}
+
+// websocketURL returns the websocket url for path below the base url with the token added to query
+func (client *Client) websocketURL(path string, query url.Values, token string) (string, error) {
+	u, err := client.baseURL()
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "http" {
+		u.Scheme = "ws"
+	} else {
+		u.Scheme = "wss"
+	}
+	values := url.Values{}
+	for key, value := range query {
+		values[key] = append([]string{}, value...)
+	}
+	if token != "" {
+		values.Set("token", token)
+	}
+	u.Path += path
+	u.RawQuery = values.Encode()
+	return u.String(), nil
+}
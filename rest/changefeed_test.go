@@ -0,0 +1,155 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const testChangeFrame = `42["query:change",{"table":"guest"},{"old_val":null,"new_val":{"name":"guest1"}}]`
+
+// newFakeSocketServer starts a socket.io server that completes the engine.io handshake and then calls handle.
+// The connection is closed when handle returns
+func newFakeSocketServer(t *testing.T, handle func(c *websocket.Conn)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(fakeSocketHandler(handle))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// fakeSocketHandler serves the socket.io endpoint of newFakeSocketServer
+func fakeSocketHandler(handle func(c *websocket.Conn)) http.HandlerFunc {
+	upgrader := websocket.Upgrader{}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/socket.io/") {
+			http.NotFound(w, r)
+			return
+		}
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		open := `0{"sid":"abc","upgrades":[],"pingInterval":25000,"pingTimeout":20000}`
+		if err := c.WriteMessage(websocket.TextMessage, []byte(open)); err != nil {
+			return
+		}
+		handle(c)
+	}
+}
+
+// floodChanges reads the registration and then sends changes until the client disconnects
+func floodChanges(c *websocket.Conn) {
+	if _, _, err := c.ReadMessage(); err != nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if err := c.WriteMessage(websocket.TextMessage, []byte(testChangeFrame)); err != nil {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitForGoroutines waits for the number of goroutines to drop to want
+func waitForGoroutines(t *testing.T, want int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > want {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("%d goroutines running, want %d\n%s", runtime.NumGoroutine(), want, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestChangeFeedCloseWithoutReading(t *testing.T) {
+	srv := newFakeSocketServer(t, floodChanges)
+	client := &Client{BaseURL: srv.URL}
+	feed, err := client.GetChangeFeedWithOptions(context.Background(), ChangeFeedOptions{Table: "guest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-feed.Data:
+		if msg.Error != nil {
+			t.Fatal(msg.Error)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change received")
+	}
+
+	// the server keeps sending while nothing reads Data
+	time.Sleep(50 * time.Millisecond)
+	closed := make(chan error)
+	go func() { closed <- feed.Close() }()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close blocked while Data was not read")
+	}
+	select {
+	case <-feed.Done:
+	default:
+		t.Fatal("Done not closed after Close")
+	}
+	if err := feed.Close(); err != nil {
+		t.Fatalf("second Close returned %v", err)
+	}
+}
+
+func TestChangeFeedDoneOnContextCancel(t *testing.T) {
+	srv := newFakeSocketServer(t, floodChanges)
+	client := &Client{BaseURL: srv.URL}
+	ctx, cancel := context.WithCancel(context.Background())
+	feed, err := client.GetChangeFeedWithOptions(ctx, ChangeFeedOptions{Table: "guest", Reconnect: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer feed.Close()
+	cancel()
+	select {
+	case <-feed.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Done not closed after the context was cancelled")
+	}
+}
+
+func TestChangeFeedCloseReleasesGoroutines(t *testing.T) {
+	srv := newFakeSocketServer(t, floodChanges)
+	client := &Client{BaseURL: srv.URL}
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 5; i++ {
+		feed, err := client.GetChangeFeedWithOptions(context.Background(), ChangeFeedOptions{Table: "guest", Reconnect: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-feed.Data
+		if err := feed.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitForGoroutines(t, before)
+}
@@ -3,18 +3,21 @@ package rest
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime/multipart"
+	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/gorilla/websocket"
 	"github.com/hashicorp/go-version"
 )
 
@@ -23,19 +26,77 @@ type authToken struct {
 }
 
 // Client is a wrapper around the hiveio rest api
+//
+// When KeepCredentials is set, Login stores the credentials as the CredentialProvider.
+// If a CredentialProvider is set, requests rejected with a 401 log in again once and are replayed.
+// Context is used for cancellation and deadlines by every method that does not take a context.
+//
+// CAFile, ClientCertFile, ClientKeyFile and PinnedFingerprints apply to rest requests, change feeds and uploads.
+// PinnedFingerprints are hex encoded sha256 fingerprints of the server certificate and are checked
+// in addition to the normal certificate verification unless AllowInsecure is set.
+//
+// HTTPClient or Transport can be set to customize how requests are sent. The tls and proxy options are
+// only applied to the default transport. A Client is safe for concurrent use once it is configured.
+//
+// Proxy is an http or socks5 url used for rest requests, change feeds and uploads. Only schemes every
+// transport supports are accepted, the websocket dialer does not support https or socks5h proxies.
+// The HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables are used when it is empty.
+//
+// BaseURL overrides Host, Port and Scheme with a full url including an optional path prefix,
+// for example https://hive.example.com/hive. When Scheme is empty, http is used for port 3000
+// and https for every other port.
+//
+// RateLimit limits requests and change feed connections per second with bursts of up to RateBurst.
+// MaxInFlight limits the number of requests running at the same time. Both are disabled when zero.
+//
+// Logger receives a debug record for every request with the method, path, status and latency.
+// Request and response bodies are included when LogBodies is set. Authorization headers are never
+// logged and password, secret, token and key fields are redacted. ClientTrace is attached to every request.
 type Client struct {
-	Host          string
-	Port          uint
-	AllowInsecure bool
-	UserAgent     string
-	httpClient    *http.Client
-	token         string
-	Context       context.Context
+	Host               string
+	Port               uint
+	Scheme             string
+	BaseURL            string
+	AllowInsecure      bool
+	CAFile             string
+	ClientCertFile     string
+	ClientKeyFile      string
+	PinnedFingerprints []string
+	Proxy              string
+	UserAgent          string
+	Retry              *RetryPolicy
+	RateLimit          float64
+	RateBurst          int
+	MaxInFlight        int
+	Logger             *slog.Logger
+	LogBodies          bool
+	ClientTrace        *httptrace.ClientTrace
+	KeepCredentials    bool
+	CredentialProvider CredentialProvider
+	HTTPClient         *http.Client
+	Transport          http.RoundTripper
+	httpClient         *http.Client
+	httpClientMu       sync.Mutex
+	limitsOnce         sync.Once
+	limiter            *rateLimiter
+	inFlight           chan struct{}
+	token              string
+	tokenMu            sync.RWMutex
+	loginMu            sync.Mutex
+	Context            context.Context
+}
+
+// getContext returns Client.Context or context.Background if it is not set
+func (client *Client) getContext() context.Context {
+	if client.Context != nil {
+		return client.Context
+	}
+	return context.Background()
 }
 
 // SetToken sets the token directly instead of calling auth
 func (client *Client) SetToken(token string) {
-	client.token = token
+	client.setToken(token)
 }
 
 func (client *Client) getTaskFromResponse(body []byte, err error) (*Task, error) {
@@ -61,13 +122,13 @@ func checkResponse(res *http.Response, err error) ([]byte, error) {
 	}
 	defer res.Body.Close()
 	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		err = (fmt.Errorf("{\"error\": %d, \"message\": %s}", res.StatusCode, body))
+		err = newAPIError(res, body)
 	}
 	return body, err
 }
 
 func (client *Client) request(method, path string, data []byte) ([]byte, error) {
-	return client.requestWithContext(context.Background(), method, path, data)
+	return client.requestWithContext(client.getContext(), method, path, data)
 }
 
 func (client *Client) requestWithContext(ctx context.Context, method, path string, data []byte) ([]byte, error) {
@@ -108,57 +169,197 @@ func (client *Client) postMultipart(path, filenameField, filepath string, params
 	headers := map[string]string{
 		"Content-Type": fmt.Sprintf("multipart/form-data; boundary=%s", writer.Boundary()),
 	}
-	return checkResponse(client.requestWithHeaders(context.Background(), "POST", path, mreader, headers, time.Second*30))
+	return checkResponse(client.requestWithHeaders(client.getContext(), "POST", path, mreader, headers, time.Second*30))
+}
+
+// getHTTPClient returns the http.Client used for requests, creating it on first use
+func (client *Client) getHTTPClient() (*http.Client, error) {
+	client.httpClientMu.Lock()
+	defer client.httpClientMu.Unlock()
+	if client.httpClient != nil {
+		return client.httpClient, nil
+	}
+	if client.HTTPClient != nil {
+		client.httpClient = client.HTTPClient
+		return client.httpClient, nil
+	}
+	transport := client.Transport
+	if transport == nil {
+		tlsConfig, err := client.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		proxy, err := client.proxyFunc()
+		if err != nil {
+			return nil, err
+		}
+		transport = &http.Transport{
+			Proxy:              proxy,
+			TLSClientConfig:    tlsConfig,
+			DisableCompression: true,
+		}
+	}
+	client.httpClient = &http.Client{Transport: transport}
+	return client.httpClient, nil
+}
+
+// proxyFunc returns the proxy selection function for Client.Proxy
+func (client *Client) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	if client.Proxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	proxyURL, err := url.Parse(client.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	switch proxyURL.Scheme {
+	case "http", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %s", proxyURL.Scheme)
+	}
+	return http.ProxyURL(proxyURL), nil
+}
+
+// cancelOnClose cancels the request context when the response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (body cancelOnClose) Close() error {
+	err := body.ReadCloser.Close()
+	body.cancel()
+	return err
+}
+
+// baseURL returns the scheme, host and path prefix used to build rest, websocket and upload urls
+func (client *Client) baseURL() (*url.URL, error) {
+	if client.BaseURL != "" {
+		u, err := url.Parse(client.BaseURL)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("unsupported url scheme: %s", u.Scheme)
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid url: %s", client.BaseURL)
+		}
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		u.RawQuery = ""
+		return u, nil
+	}
+	scheme := client.Scheme
+	if scheme == "" {
+		scheme = "https"
+		if client.Port == 3000 {
+			scheme = "http"
+		}
+	}
+	if scheme != "http" && scheme != "https" {
+		return nil, fmt.Errorf("unsupported url scheme: %s", scheme)
+	}
+	host := client.Host
+	if client.Port != 0 {
+		host = net.JoinHostPort(client.Host, strconv.Itoa(int(client.Port)))
+	}
+	return &url.URL{Scheme: scheme, Host: host}, nil
+}
+
+// hostname returns the hostname the client connects to
+func (client *Client) hostname() string {
+	if u, err := client.baseURL(); err == nil {
+		return u.Hostname()
+	}
+	return client.Host
 }
 
 func (client *Client) requestWithHeaders(ctx context.Context, method, path string, body io.Reader, headers map[string]string, timeout time.Duration) (*http.Response, error) {
-	protocol := "https"
-	if client.Port == 3000 {
-		protocol = "http"
+	base, err := client.baseURL()
+	if err != nil {
+		return nil, err
 	}
 	//TODO: separate queryString from path in function arguments
-	urlString := fmt.Sprintf("%s://%s:%d/api/%s", protocol, client.Host, client.Port, path)
-	u, err := url.Parse(urlString)
+	u, err := url.Parse(base.String() + "/api/" + path)
 	if err != nil {
 		return nil, err
 	}
-	if client.httpClient == nil {
-		tr := &http.Transport{
-			TLSClientConfig:    &tls.Config{InsecureSkipVerify: client.AllowInsecure},
-			DisableCompression: true,
-		}
-		client.httpClient = &http.Client{Transport: tr}
+	httpClient, err := client.getHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	if client.ClientTrace != nil {
+		ctx = httptrace.WithClientTrace(ctx, client.ClientTrace)
+	}
+	cancelTimeout := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+	}
+	release, err := client.acquire(ctx)
+	if err != nil {
+		cancelTimeout()
+		return nil, err
+	}
+	cancel := func() {
+		release()
+		cancelTimeout()
 	}
-	client.httpClient.Timeout = timeout
 
 	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
 	for key, val := range headers {
 		req.Header.Add(key, val)
 	}
-	if client.token != "" {
-		req.Header.Add("Authorization", "Bearer "+client.token)
+	token := client.getToken()
+	if token != "" {
+		req.Header.Add("Authorization", "Bearer "+token)
 	}
 	if client.UserAgent != "" {
 		req.Header.Add("User-Agent", client.UserAgent)
 	}
-	return client.httpClient.Do(req)
+	res, err := client.doWithRetry(httpClient, req)
+	if err == nil && res.StatusCode == http.StatusUnauthorized && client.canReplay(req, path) {
+		// release the in flight slot while logging in so the login request can run
+		release()
+		if client.reauthenticate(ctx, token) == nil {
+			res.Body.Close()
+			if release, err = client.acquire(ctx); err != nil {
+				release = func() {}
+			} else {
+				res, err = client.replay(httpClient, req)
+			}
+		}
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
 }
 
 // Login attempts to connect to the server specified in Client with the provided username, password, and realm
 func (client *Client) Login(username, password, realm string) error {
-	if password == "" && (client.Host == "localhost" || client.Host == "::1" || client.Host == "127.0.0.1") {
+	if hostname := client.hostname(); password == "" && (hostname == "localhost" || hostname == "::1" || hostname == "127.0.0.1") {
 		return nil
 	}
-	jsonData := map[string]string{"username": username, "password": password, "realm": realm}
+	if client.KeepCredentials && client.CredentialProvider == nil {
+		client.CredentialProvider = StaticCredentials(username, password, realm)
+	}
+	return client.login(client.getContext(), Credentials{Username: username, Password: password, Realm: realm})
+}
+
+func (client *Client) login(ctx context.Context, creds Credentials) error {
+	jsonData := map[string]string{"username": creds.Username, "password": creds.Password, "realm": creds.Realm}
 	jsonValue, err := json.Marshal(jsonData)
 	if err != nil {
 		return err
 	}
-	body, err := client.request("POST", "auth", jsonValue)
+	body, err := client.requestWithContext(ctx, "POST", "auth", jsonValue)
 	if err != nil {
 		return err
 	}
@@ -166,153 +367,11 @@ func (client *Client) Login(username, password, realm string) error {
 	auth := authToken{}
 	err = json.Unmarshal(body, &auth)
 	if err == nil {
-		client.token = auth.Token
+		client.setToken(auth.Token)
 	}
 	return err
 }
 
-// ChangeFeed wrapper around a websocket to monitor database changes
-type ChangeFeed struct {
-	Data chan ChangeFeedMessage
-	Done chan struct{}
-	conn *websocket.Conn
-}
-
-// ChangeFeedMessage contains a change from the database
-type ChangeFeedMessage struct {
-	OldValue json.RawMessage `json:"old_val"`
-	NewValue json.RawMessage `json:"new_val"`
-	Error    error
-}
-
-func (feed *ChangeFeed) monitorChangeFeed(ctx context.Context) {
-	defer close(feed.Done)
-	defer feed.conn.Close()
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			var msg ChangeFeedMessage
-			_, message, err := feed.conn.ReadMessage()
-			if err != nil {
-				msg.Error = err
-				feed.Data <- msg
-				return
-			}
-			if len(message) < 3 {
-				continue
-			}
-			if string(message[:2]) == "44" {
-				msg.Error = fmt.Errorf("%s", message[2:])
-				feed.Data <- msg
-			} else if string(message[:2]) != "42" {
-				continue
-			}
-
-			var jsonMsg []json.RawMessage
-			err = json.Unmarshal(message[2:], &jsonMsg)
-			if err != nil {
-				msg.Error = err
-				feed.Data <- msg
-				continue
-			}
-			if len(jsonMsg) < 3 {
-				msg.Error = fmt.Errorf("invalid changefeed message")
-				feed.Data <- msg
-				continue
-			}
-			if strings.Contains(string(jsonMsg[0]), "initial") {
-				var newValue []json.RawMessage
-				err = json.Unmarshal(jsonMsg[2], &newValue)
-				if err != nil {
-					msg.Error = err
-				} else if len(newValue) > 0 {
-					msg.NewValue = newValue[0]
-				}
-			} else {
-				err = json.Unmarshal(jsonMsg[2], &msg)
-				if err != nil {
-					msg.Error = err
-				}
-			}
-			feed.Data <- msg
-		}
-	}
-}
-
-func (feed *ChangeFeed) changeFeedKeepAlive(ctx context.Context, timeout time.Duration) {
-	ticker := time.NewTicker(timeout)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			feed.conn.WriteMessage(websocket.TextMessage, []byte("2"))
-		case <-feed.Done:
-			return
-		}
-	}
-}
-
-// Close disconnects the changefeed websocket
-func (feed *ChangeFeed) Close() error {
-	return feed.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
-}
-
-// GetChangeFeed returns a ChangeFeed for monitoring the specified table
-// filter can be used to limit the changes monitored.
-// example to monitor a single task:
-// client.GetChangeFeed("task", map[string]string{"id": task.ID})
-func (client *Client) GetChangeFeed(table string, filter map[string]string, includeInitial bool) (*ChangeFeed, error) {
-	return client.GetChangeFeedWithContext(context.Background(), table, filter, includeInitial)
-}
-
-// GetChangeFeedWithContext returns a ChangeFeed for monitoring the specified table with a custom context
-// filter can be used to limit the changes monitored.
-// example to monitor a single task:
-// client.GetChangeFeed("task", map[string]string{"id": task.ID})
-func (client *Client) GetChangeFeedWithContext(ctx context.Context, table string, filter map[string]string, includeInitial bool) (*ChangeFeed, error) {
-	protocol := "wss"
-	var token string
-	if client.Port == 3000 {
-		protocol = "ws"
-	}
-	if client.token != "" {
-		token = "token=" + client.token + "&"
-	}
-	u := url.URL{Scheme: protocol, Host: fmt.Sprintf("%s:%d", client.Host, client.Port), Path: "/socket.io/", RawQuery: token + "transport=websocket"}
-	dialer := websocket.Dialer{
-		TLSClientConfig:  &tls.Config{InsecureSkipVerify: true},
-		HandshakeTimeout: 20 * time.Second,
-	}
-
-	c, _, err := dialer.DialContext(ctx, u.String(), nil)
-	if err != nil {
-		return nil, err
-	}
-	options := map[string]interface{}{"table": table, "includeInitial": includeInitial, "filter": filter}
-	jsonData := []interface{}{"query:change:register", options}
-	jsonValue, err := json.Marshal(jsonData)
-	if err != nil {
-		return nil, err
-	}
-	err = c.WriteMessage(websocket.TextMessage, append([]byte("42"), jsonValue...))
-	if err != nil {
-		return nil, err
-	}
-
-	done := make(chan struct{})
-	incomingData := make(chan ChangeFeedMessage)
-	feed := ChangeFeed{Data: incomingData, Done: done, conn: c}
-
-	go feed.changeFeedKeepAlive(ctx, 25*time.Second)
-	go feed.monitorChangeFeed(ctx)
-
-	return &feed, nil
-}
-
 // HostVersion returns the software version of the host the client is connected to
 func (client *Client) HostVersion() (Version, error) {
 	var version Version
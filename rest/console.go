@@ -0,0 +1,93 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// GuestConsole is a connection to the console of a guest.
+// Read and Write carry the raw RFB (VNC) stream, so it can be bridged to any VNC client
+type GuestConsole struct {
+	conn    *websocket.Conn
+	reader  io.Reader
+	writeMu sync.Mutex
+	closed  sync.Once
+}
+
+// OpenConsole opens a websocket to the console of a running guest
+func (guest *Guest) OpenConsole(ctx context.Context, client *Client) (*GuestConsole, error) {
+	if guest.Name == "" {
+		return nil, errors.New("name cannot be empty")
+	}
+	header := http.Header{"Sec-WebSocket-Protocol": {"binary"}}
+	conn, err := client.dialWebsocket(ctx, "/api/guest/"+url.PathEscape(guest.Name)+"/console", nil, header)
+	if err != nil {
+		return nil, err
+	}
+	return &GuestConsole{conn: conn}, nil
+}
+
+// Read reads console data sent by the guest
+func (console *GuestConsole) Read(p []byte) (int, error) {
+	for {
+		if console.reader == nil {
+			messageType, reader, err := console.conn.NextReader()
+			if err != nil {
+				if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					return 0, io.EOF
+				}
+				return 0, err
+			}
+			if messageType != websocket.BinaryMessage {
+				continue
+			}
+			console.reader = reader
+		}
+		n, err := console.reader.Read(p)
+		if err == io.EOF {
+			console.reader = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+// Write sends console data to the guest
+func (console *GuestConsole) Write(p []byte) (int, error) {
+	console.writeMu.Lock()
+	defer console.writeMu.Unlock()
+	if err := console.conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the console connection
+func (console *GuestConsole) Close() error {
+	var err error
+	console.closed.Do(func() {
+		console.writeMu.Lock()
+		console.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		console.writeMu.Unlock()
+		err = console.conn.Close()
+	})
+	return err
+}
+
+// Screenshot returns a png image of the guest console
+func (guest *Guest) Screenshot(client *Client) ([]byte, error) {
+	if guest.Name == "" {
+		return nil, errors.New("name cannot be empty")
+	}
+	return client.request("GET", "guest/"+url.PathEscape(guest.Name)+"/screenshot", nil)
+}
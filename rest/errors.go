@@ -0,0 +1,114 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sentinel errors that can be used with errors.Is to check the type of an APIError
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrServer       = errors.New("server error")
+)
+
+// APIError is returned when the rest api responds with a non 2xx status code
+type APIError struct {
+	StatusCode int
+	Method     string
+	Path       string
+	Message    string
+	Body       []byte
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("{\"error\": %d, \"message\": %s}", e.StatusCode, e.Body)
+}
+
+// Is allows comparing an APIError to the sentinel errors with errors.Is
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+func newAPIError(res *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: res.StatusCode,
+		Message:    string(body),
+		Body:       body,
+	}
+	if res.Request != nil && res.Request.URL != nil {
+		apiErr.Method = res.Request.Method
+		apiErr.Path = res.Request.URL.Path
+	}
+	var msg struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &msg); err == nil {
+		if msg.Message != "" {
+			apiErr.Message = msg.Message
+		} else if msg.Error != "" {
+			apiErr.Message = msg.Error
+		}
+	}
+	return apiErr
+}
+
+// notFoundError is returned by lookups that search a list for a matching record
+type notFoundError string
+
+func (e notFoundError) Error() string {
+	return string(e)
+}
+
+func (e notFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// IsNotFound returns true if err was caused by a missing record
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsConflict returns true if err was caused by a 409 response
+func IsConflict(err error) bool {
+	return errors.Is(err, ErrConflict)
+}
+
+// IsUnauthorized returns true if err was caused by a 401 response
+func IsUnauthorized(err error) bool {
+	return errors.Is(err, ErrUnauthorized)
+}
+
+// IsServerError returns true if err was caused by a 5xx response
+func IsServerError(err error) bool {
+	return errors.Is(err, ErrServer)
+}
+
+// StatusCode returns the http status code of an APIError or 0 if err is not an APIError
+func StatusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
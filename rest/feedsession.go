@@ -0,0 +1,372 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrFeedSessionClosed is returned when subscribing to a FeedSession that has stopped
+var ErrFeedSessionClosed = errors.New("feed session closed")
+
+// ErrFeedSessionTable is delivered to the feeds of a FeedSession subscribed to several tables when a change
+// does not say which table it belongs to
+var ErrFeedSessionTable = errors.New("change feed message does not identify its table")
+
+// FeedSessionOptions configures a FeedSession created with NewFeedSession
+type FeedSessionOptions struct {
+	// Reconnect dials the session again with backoff when the websocket fails and registers every subscription again
+	Reconnect bool
+	// MaxReconnectAttempts limits consecutive failed reconnect attempts, 0 retries until the context is done
+	MaxReconnectAttempts int
+	// MinBackoff and MaxBackoff bound the delay between reconnect attempts. Defaults are 1s and 30s
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// FeedSession shares one socket.io connection and keep-alive loop between many change feed subscriptions.
+// Each table and filter is registered with the server once and every change is routed to the feeds of its
+// table whose filter matches it. The table is read from the registration the server sends back with each change.
+// Done is closed when the session stops because of an error, Close, or the context being cancelled.
+type FeedSession struct {
+	Done      chan struct{}
+	client    *Client
+	options   FeedSessionOptions
+	parentCtx context.Context
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	conn      *socketConn
+	feeds     map[*ChangeFeed]struct{}
+	// registrations counts the register events sent on the current connection for each feedKey
+	registrations map[string]int
+	stopped       bool
+	closeOnce     sync.Once
+	closeErr      error
+}
+
+// NewFeedSession connects a FeedSession to the server. Subscriptions are added with Subscribe
+func (client *Client) NewFeedSession(ctx context.Context, options FeedSessionOptions) (*FeedSession, error) {
+	c, err := client.dialChangeFeedSocket(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessionCtx, cancel := context.WithCancel(ctx)
+	session := &FeedSession{
+		Done:          make(chan struct{}),
+		client:        client,
+		options:       options,
+		parentCtx:     ctx,
+		ctx:           sessionCtx,
+		cancel:        cancel,
+		conn:          c,
+		feeds:         make(map[*ChangeFeed]struct{}),
+		registrations: make(map[string]int),
+	}
+	session.wg.Add(2)
+	go session.keepAlive()
+	go session.monitor()
+	return session, nil
+}
+
+// feedKey identifies a table and filter registration
+func feedKey(table string, filter map[string]string) string {
+	key, _ := json.Marshal(map[string]interface{}{"table": table, "filter": filter})
+	return string(key)
+}
+
+// Subscribe registers options.Table and options.Filter on the session and returns a ChangeFeed receiving
+// the matching changes. Closing the returned feed unsubscribes it without affecting the other subscriptions.
+// The reconnect settings in options are ignored, the session reconnects all of its feeds together.
+func (session *FeedSession) Subscribe(options ChangeFeedOptions) (*ChangeFeed, error) {
+	feedCtx, cancel := context.WithCancel(session.parentCtx)
+	feed := &ChangeFeed{
+		Data:    make(chan ChangeFeedMessage),
+		Done:    make(chan struct{}),
+		client:  session.client,
+		options: options,
+		ctx:     feedCtx,
+		cancel:  cancel,
+		session: session,
+		notify:  make(chan struct{}, 1),
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.stopped {
+		cancel()
+		return nil, ErrFeedSessionClosed
+	}
+	// a filter that is already registered only needs to be sent again to fetch the initial values
+	if !session.registered(options.Table, options.Filter) || options.IncludeInitial {
+		err := session.register(options.Table, options.Filter, options.IncludeInitial)
+		if err != nil && !session.options.Reconnect {
+			cancel()
+			return nil, err
+		}
+	}
+	feed.initial = options.IncludeInitial
+	session.feeds[feed] = struct{}{}
+	feed.wg.Add(1)
+	go feed.forward()
+	return feed, nil
+}
+
+// unsubscribe removes feed from the session and unregisters its filter when no other feed uses it.
+// The filter is unregistered once for every time it was registered, so a server that counts registrations
+// does not keep sending its changes
+func (session *FeedSession) unsubscribe(feed *ChangeFeed) error {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if _, ok := session.feeds[feed]; !ok {
+		return nil
+	}
+	delete(session.feeds, feed)
+	if session.stopped || session.registered(feed.options.Table, feed.options.Filter) {
+		return nil
+	}
+	key := feedKey(feed.options.Table, feed.options.Filter)
+	count := session.registrations[key]
+	delete(session.registrations, key)
+	for i := 0; i < count; i++ {
+		if err := session.writePacket("query:change:unregister", feed.options.Table, feed.options.Filter, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// register sends a register event and counts it. session.mu must be held
+func (session *FeedSession) register(table string, filter map[string]string, includeInitial bool) error {
+	if err := session.writePacket("query:change:register", table, filter, includeInitial); err != nil {
+		return err
+	}
+	session.registrations[feedKey(table, filter)]++
+	return nil
+}
+
+// registered returns true if a feed is subscribed to table and filter. session.mu must be held
+func (session *FeedSession) registered(table string, filter map[string]string) bool {
+	key := feedKey(table, filter)
+	for feed := range session.feeds {
+		if feedKey(feed.options.Table, feed.options.Filter) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// writePacket sends a change feed register or unregister event. session.mu must be held
+func (session *FeedSession) writePacket(event, table string, filter map[string]string, includeInitial bool) error {
+	return registerChangeFeed(session.conn, event, table, filter, includeInitial)
+}
+
+func (session *FeedSession) getConn() *socketConn {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.conn
+}
+
+// route queues msg on every feed subscribed to table whose filter matches the changed record.
+// A change without a table can only be routed when every feed uses the same table
+func (session *FeedSession) route(msg ChangeFeedMessage, table string) {
+	var record map[string]interface{}
+	if json.Unmarshal(msg.NewValue, &record) != nil || record == nil {
+		json.Unmarshal(msg.OldValue, &record)
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if table == "" && msg.Error == nil && session.multipleTables() {
+		msg = ChangeFeedMessage{Error: ErrFeedSessionTable}
+	}
+	for feed := range session.feeds {
+		if msg.Error == nil {
+			if table != "" && table != feed.options.Table {
+				continue
+			}
+			if msg.Initial && !feed.initial {
+				continue
+			}
+			if !filterMatches(feed.options.Filter, record) {
+				continue
+			}
+		}
+		feed.enqueue(msg, false)
+	}
+}
+
+// multipleTables returns true if the feeds are subscribed to more than one table. session.mu must be held
+func (session *FeedSession) multipleTables() bool {
+	table := ""
+	for feed := range session.feeds {
+		if table != "" && feed.options.Table != table {
+			return true
+		}
+		table = feed.options.Table
+	}
+	return false
+}
+
+// broadcast queues msg on every feed
+func (session *FeedSession) broadcast(msg ChangeFeedMessage) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	for feed := range session.feeds {
+		feed.enqueue(msg, false)
+	}
+}
+
+// filterMatches returns true if every filter field equals the field of record. Keys may use dots to
+// reach nested fields, for example "guest.poolId"
+func filterMatches(filter map[string]string, record map[string]interface{}) bool {
+	for key, want := range filter {
+		var value interface{} = record
+		for _, field := range strings.Split(key, ".") {
+			fields, ok := value.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			if value, ok = fields[field]; !ok {
+				return false
+			}
+		}
+		if fmt.Sprint(value) != want {
+			return false
+		}
+	}
+	return true
+}
+
+func (session *FeedSession) monitor() {
+	defer session.wg.Done()
+	defer close(session.Done)
+	defer session.cancel()
+	defer func() {
+		session.mu.Lock()
+		session.stopped = true
+		session.mu.Unlock()
+	}()
+	for {
+		packet, err := session.getConn().readPacket()
+		if session.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			if session.options.Reconnect {
+				err = session.reconnect(err)
+			}
+			if err != nil {
+				session.stop(ChangeFeedMessage{Error: err})
+				return
+			}
+			session.broadcast(ChangeFeedMessage{Reconnected: true})
+			continue
+		}
+		if msg, table, ok := parseChangeFeedPacket(packet); ok {
+			session.route(msg, table)
+		}
+	}
+}
+
+// stop marks the session as stopped and delivers msg as the last message of every feed
+func (session *FeedSession) stop(msg ChangeFeedMessage) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.stopped = true
+	for feed := range session.feeds {
+		feed.enqueue(msg, true)
+	}
+}
+
+// reconnect dials the session again and registers every subscribed table and filter
+func (session *FeedSession) reconnect(cause error) error {
+	ctx := session.ctx
+	options := session.options
+	return session.client.redial(ctx, cause, options.MaxReconnectAttempts, options.MinBackoff, options.MaxBackoff, nil, func() error {
+		conn, err := session.client.dialChangeFeedSocket(ctx)
+		if err != nil {
+			return err
+		}
+		session.mu.Lock()
+		defer session.mu.Unlock()
+		if ctx.Err() != nil {
+			conn.Close()
+			return ctx.Err()
+		}
+		oldConn, oldRegistrations := session.conn, session.registrations
+		session.conn = conn
+		session.registrations = make(map[string]int)
+		registered := make(map[string]bool)
+		for feed := range session.feeds {
+			key := feedKey(feed.options.Table, feed.options.Filter)
+			includeInitial := feed.options.IncludeInitial || feed.options.RefetchOnReconnect
+			feed.initial = includeInitial
+			if registered[key] && !includeInitial {
+				continue
+			}
+			registered[key] = true
+			if err := session.register(feed.options.Table, feed.options.Filter, includeInitial); err != nil {
+				session.conn, session.registrations = oldConn, oldRegistrations
+				conn.Close()
+				return err
+			}
+		}
+		oldConn.Close()
+		return nil
+	})
+}
+
+// keepAlive pings the server and closes the websocket when the session is stopped
+func (session *FeedSession) keepAlive() {
+	defer session.wg.Done()
+	ticker := time.NewTicker(session.getConn().pingInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-session.ctx.Done():
+			session.mu.Lock()
+			session.conn.Close()
+			session.mu.Unlock()
+			return
+		case <-ticker.C:
+			session.getConn().ping()
+		}
+	}
+}
+
+// Close disconnects the session websocket, stops every subscribed feed and waits for their goroutines to exit
+func (session *FeedSession) Close() error {
+	session.closeOnce.Do(func() {
+		session.mu.Lock()
+		session.stopped = true
+		feeds := make([]*ChangeFeed, 0, len(session.feeds))
+		for feed := range session.feeds {
+			feeds = append(feeds, feed)
+		}
+		if session.ctx.Err() == nil {
+			deadline := time.Now().Add(time.Second)
+			session.closeErr = session.conn.closeGracefully(deadline)
+		}
+		session.mu.Unlock()
+		session.cancel()
+		session.wg.Wait()
+		for _, feed := range feeds {
+			feed.Close()
+		}
+	})
+	return session.closeErr
+}
+
+// SessionWatch subscribes to options.Table on session and decodes the records into T
+func SessionWatch[T any](session *FeedSession, options ChangeFeedOptions) (*TypedChangeFeed[T], error) {
+	feed, err := session.Subscribe(options)
+	if err != nil {
+		return nil, err
+	}
+	return watchFeed[T](feed), nil
+}
@@ -0,0 +1,144 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// recordFrames sends every frame received from the client to frames until the client disconnects
+func recordFrames(frames chan<- string) func(c *websocket.Conn) {
+	return func(c *websocket.Conn) {
+		for {
+			_, frame, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			frames <- string(frame)
+		}
+	}
+}
+
+func nextFrame(t *testing.T, frames <-chan string) string {
+	t.Helper()
+	select {
+	case frame := <-frames:
+		return frame
+	case <-time.After(5 * time.Second):
+		t.Fatal("no frame received")
+		return ""
+	}
+}
+
+func TestFeedSessionRoutesByTable(t *testing.T) {
+	srv := newFakeSocketServer(t, func(c *websocket.Conn) {
+		for i := 0; i < 2; i++ {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+		for _, frame := range []string{
+			`42["query:change",{"table":"pool","filter":null},{"old_val":null,"new_val":{"id":"p1","name":"pool1"}}]`,
+			testChangeFrame,
+			`42["query:change",{},{"old_val":null,"new_val":{"name":"unknown"}}]`,
+		} {
+			if err := c.WriteMessage(websocket.TextMessage, []byte(frame)); err != nil {
+				return
+			}
+		}
+		c.ReadMessage()
+	})
+	client := &Client{BaseURL: srv.URL}
+	session, err := client.NewFeedSession(context.Background(), FeedSessionOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	guests, err := SessionWatch[Guest](session, ChangeFeedOptions{Table: "guest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pools, err := SessionWatch[Pool](session, ChangeFeedOptions{Table: "pool"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case change := <-guests.Changes:
+		if change.New == nil || change.New.Name != "guest1" {
+			t.Fatalf("guest feed got %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no guest change received")
+	}
+	select {
+	case change := <-pools.Changes:
+		if change.New == nil || change.New.Name != "pool1" {
+			t.Fatalf("pool feed got %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no pool change received")
+	}
+	// a change without a table is reported to both feeds instead of being guessed
+	for _, errs := range []<-chan error{guests.Errors, pools.Errors} {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, ErrFeedSessionTable) {
+				t.Fatalf("got %v, want ErrFeedSessionTable", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no error for the change without a table")
+		}
+	}
+	select {
+	case change := <-guests.Changes:
+		t.Fatalf("guest feed got an extra change %+v", change)
+	case change := <-pools.Changes:
+		t.Fatalf("pool feed got an extra change %+v", change)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestFeedSessionUnregistersEveryRegistration(t *testing.T) {
+	frames := make(chan string, 10)
+	srv := newFakeSocketServer(t, recordFrames(frames))
+	client := &Client{BaseURL: srv.URL}
+	session, err := client.NewFeedSession(context.Background(), FeedSessionOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	options := ChangeFeedOptions{Table: "guest", Filter: map[string]string{"name": "guest1"}}
+	first, err := session.Subscribe(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options.IncludeInitial = true
+	second, err := session.Subscribe(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if frame := nextFrame(t, frames); !strings.Contains(frame, "query:change:register") {
+			t.Fatalf("frame %d is %s, want a register event", i, frame)
+		}
+	}
+
+	first.Close()
+	select {
+	case frame := <-frames:
+		t.Fatalf("unregistered while a feed still uses the filter: %s", frame)
+	case <-time.After(50 * time.Millisecond):
+	}
+	second.Close()
+	for i := 0; i < 2; i++ {
+		if frame := nextFrame(t, frames); !strings.Contains(frame, "query:change:unregister") {
+			t.Fatalf("frame %d is %s, want an unregister event", i, frame)
+		}
+	}
+}
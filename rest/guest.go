@@ -433,6 +433,10 @@ func (guest *Guest) Restore(client *Client, storageId, backup string) (*Task, er
 
 // Migrate migrate a guest to a different host
 func (guest *Guest) Migrate(client *Client, destinationHostid string) error {
+	return guest.migrate(client.getContext(), client, destinationHostid)
+}
+
+func (guest *Guest) migrate(ctx context.Context, client *Client, destinationHostid string) error {
 	if guest.Name == "" {
 		return errors.New("name cannot be empty")
 	}
@@ -441,7 +445,68 @@ func (guest *Guest) Migrate(client *Client, destinationHostid string) error {
 	if err != nil {
 		return err
 	}
-	_, err = client.request("POST", "guest/"+url.PathEscape(guest.Name)+"/migrate", jsonValue)
+	_, err = client.requestWithContext(ctx, "POST", "guest/"+url.PathEscape(guest.Name)+"/migrate", jsonValue)
+	return err
+}
+
+// snapshotsPath returns the path of the guest snapshots, or of the named snapshot
+func (guest *Guest) snapshotsPath(name string) string {
+	path := "guest/" + url.PathEscape(guest.Name) + "/snapshots"
+	if name != "" {
+		path += "/" + url.PathEscape(name)
+	}
+	return path
+}
+
+// ListSnapshots returns the disk snapshots of a guest
+func (guest *Guest) ListSnapshots(client *Client) ([]GuestSnapshot, error) {
+	if guest.Name == "" {
+		return nil, errors.New("name cannot be empty")
+	}
+	snapshots := []GuestSnapshot{}
+	body, err := client.request("GET", guest.snapshotsPath(""), nil)
+	if err != nil {
+		return snapshots, err
+	}
+	err = json.Unmarshal(body, &snapshots)
+	return snapshots, err
+}
+
+// CreateSnapshot starts a task creating a snapshot of the guest disks
+func (guest *Guest) CreateSnapshot(client *Client, name string) (*Task, error) {
+	if guest.Name == "" {
+		return nil, errors.New("name cannot be empty")
+	}
+	if name == "" {
+		return nil, errors.New("snapshot name cannot be empty")
+	}
+	jsonValue, err := json.Marshal(map[string]string{"name": name})
+	if err != nil {
+		return nil, err
+	}
+	return client.getTaskFromResponse(client.request("POST", guest.snapshotsPath(""), jsonValue))
+}
+
+// RevertSnapshot starts a task reverting the guest disks to a snapshot
+func (guest *Guest) RevertSnapshot(client *Client, name string) (*Task, error) {
+	if guest.Name == "" {
+		return nil, errors.New("name cannot be empty")
+	}
+	if name == "" {
+		return nil, errors.New("snapshot name cannot be empty")
+	}
+	return client.getTaskFromResponse(client.request("POST", guest.snapshotsPath(name)+"/revert", nil))
+}
+
+// DeleteSnapshot deletes a guest snapshot
+func (guest *Guest) DeleteSnapshot(client *Client, name string) error {
+	if guest.Name == "" {
+		return errors.New("name cannot be empty")
+	}
+	if name == "" {
+		return errors.New("snapshot name cannot be empty")
+	}
+	_, err := client.request("DELETE", guest.snapshotsPath(name), nil)
 	return err
 }
 
@@ -470,7 +535,7 @@ func GuestHasIpAddress(guest Guest) bool {
 
 // WaitForGuest waits for a guest state to match the targetState
 func (guest Guest) WaitForGuest(client *Client, timeout time.Duration) error {
-	return guest.WaitForGuestWithContext(context.Background(), client, timeout)
+	return guest.WaitForGuestWithContext(client.getContext(), client, timeout)
 }
 
 // WaitForGuestWithContext waits for a guest state to match the targetState
@@ -483,36 +548,8 @@ func (guest Guest) WaitForGuestChange(ctx context.Context, client *Client, timeo
 	if validate(guest) {
 		return nil
 	}
-	newVal := Guest{}
-	feed, err := client.GetChangeFeedWithContext(ctx, "guest", map[string]string{"name": guest.Name}, false)
-	if err != nil {
-		return err
-	}
-	timer := time.NewTimer(timeout)
-	if timeout <= 0 && !timer.Stop() {
-		<-timer.C
-	}
-	for {
-		select {
-		case <-timer.C:
-			return fmt.Errorf("timed out")
-		case msg := <-feed.Data:
-			if msg.Error != nil {
-				feed.Close()
-				return msg.Error
-			}
-			err = json.Unmarshal(msg.NewValue, &newVal)
-			if err != nil {
-				err = fmt.Errorf("error with json unmarshal: %v", err)
-				feed.Close()
-				return err
-			}
-			if validate(newVal) {
-				feed.Close()
-				return nil
-			}
-		}
-	}
+	_, err := WaitFor(ctx, client, GuestTarget(client, guest.Name), timeout, validate)
+	return err
 }
 
 // ExternalGuest is used to add external guest records to the system
@@ -0,0 +1,64 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGuestSnapshotPaths(t *testing.T) {
+	requests := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath())
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/task/t1":
+			w.Write([]byte(`{"id":"t1","name":"snapshot"}`))
+		case "/api/guest/vm 1/snapshots":
+			if r.Method == "GET" {
+				w.Write([]byte(`[]`))
+				return
+			}
+			w.Write([]byte(`{"taskId":"t1"}`))
+		case "/api/guest/vm 1/snapshots/s 1/revert":
+			w.Write([]byte(`{"taskId":"t1"}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer srv.Close()
+	client := &Client{BaseURL: srv.URL}
+	guest := Guest{Name: "vm 1"}
+
+	if _, err := guest.ListSnapshots(client); err != nil {
+		t.Fatal(err)
+	}
+	task, err := guest.CreateSnapshot(client, "s 1")
+	if err != nil || task.ID != "t1" {
+		t.Fatalf("create returned %v, %v", task, err)
+	}
+	task, err = guest.RevertSnapshot(client, "s 1")
+	if err != nil || task.ID != "t1" {
+		t.Fatalf("revert returned %v, %v", task, err)
+	}
+	if err := guest.DeleteSnapshot(client, "s 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"GET /api/guest/vm%201/snapshots",
+		"POST /api/guest/vm%201/snapshots",
+		"GET /api/task/t1",
+		"POST /api/guest/vm%201/snapshots/s%201/revert",
+		"GET /api/task/t1",
+		"DELETE /api/guest/vm%201/snapshots/s%201",
+	}
+	if len(requests) != len(want) {
+		t.Fatalf("got requests %v, want %v", requests, want)
+	}
+	for i := range want {
+		if requests[i] != want[i] {
+			t.Fatalf("got requests %v, want %v", requests, want)
+		}
+	}
+}
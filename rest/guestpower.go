@@ -0,0 +1,113 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Guest states the power operations wait for
+const (
+	GuestStateRunning = "running"
+	GuestStateReady   = "ready"
+	GuestStateStopped = "stopped"
+)
+
+const defaultForceTimeout = 2 * time.Minute
+
+// GuestPowerOptions configures the power operations that wait for the resulting guest state
+type GuestPowerOptions struct {
+	// Timeout is how long to wait for the guest to reach State. 0 waits until ctx is done
+	Timeout time.Duration
+	// State overrides the state to wait for. Poweron, Reboot and Reset default to running and Shutdown and Poweroff to stopped
+	State string
+	// Force powers off a guest that has not stopped when a shutdown times out
+	Force bool
+	// ForceTimeout is how long to wait for the guest to stop after it is forced off. Defaults to 2m
+	ForceTimeout time.Duration
+	// OnForce is called before a guest that did not shut down in time is powered off
+	OnForce func(guest *Guest)
+}
+
+// GuestInState returns true if the guest is in state. A ready guest is also running
+func GuestInState(guest Guest, state string) bool {
+	if state == GuestStateRunning && guest.GuestState == GuestStateReady {
+		return true
+	}
+	return guest.GuestState == state
+}
+
+func (options GuestPowerOptions) state(defaultState string) string {
+	if options.State != "" {
+		return options.State
+	}
+	return defaultState
+}
+
+// waitForState calls action and waits for the guest to reach state. The change feed is opened before action is
+// called, so when restart is true and the guest must first leave the state a short reboot is not missed
+func (guest *Guest) waitForState(ctx context.Context, client *Client, timeout time.Duration, state string, restart bool, action func(*Guest, *Client) error) error {
+	left := !restart
+	var actionErr error
+	_, err := waitForAfter(ctx, client, GuestTarget(client, guest.Name), timeout, func(context.Context) error {
+		actionErr = action(guest, client)
+		return actionErr
+	}, func(g Guest) bool {
+		if !GuestInState(g, state) {
+			left = true
+			return false
+		}
+		return left
+	})
+	if actionErr != nil {
+		return actionErr
+	}
+	if err != nil {
+		return fmt.Errorf("guest %s did not reach state %s: %w", guest.Name, state, err)
+	}
+	return nil
+}
+
+// PoweronAndWait powers on the guest and waits until it is running
+func (guest *Guest) PoweronAndWait(ctx context.Context, client *Client, options GuestPowerOptions) error {
+	return guest.waitForState(ctx, client, options.Timeout, options.state(GuestStateRunning), false, (*Guest).Poweron)
+}
+
+// PoweroffAndWait forces the guest off and waits until it is stopped
+func (guest *Guest) PoweroffAndWait(ctx context.Context, client *Client, options GuestPowerOptions) error {
+	return guest.waitForState(ctx, client, options.Timeout, options.state(GuestStateStopped), false, (*Guest).Poweroff)
+}
+
+// RebootAndWait reboots the guest and waits until it is running again
+func (guest *Guest) RebootAndWait(ctx context.Context, client *Client, options GuestPowerOptions) error {
+	return guest.waitForState(ctx, client, options.Timeout, options.state(GuestStateRunning), true, (*Guest).Reboot)
+}
+
+// ResetAndWait resets the guest and waits until it is running again
+func (guest *Guest) ResetAndWait(ctx context.Context, client *Client, options GuestPowerOptions) error {
+	return guest.waitForState(ctx, client, options.Timeout, options.state(GuestStateRunning), true, (*Guest).Reset)
+}
+
+// ShutdownAndWait shuts down the guest and waits until it is stopped.
+// With options.Force a guest that has not stopped before options.Timeout is powered off
+func (guest *Guest) ShutdownAndWait(ctx context.Context, client *Client, options GuestPowerOptions) error {
+	state := options.state(GuestStateStopped)
+	err := guest.waitForState(ctx, client, options.Timeout, state, false, (*Guest).Shutdown)
+	if err == nil || !options.Force || !errors.Is(err, ErrWaitTimeout) {
+		return err
+	}
+
+	if client.logEnabled(ctx) {
+		client.Logger.LogAttrs(ctx, slog.LevelDebug, "guest shutdown timed out, powering off", slog.String("guest", guest.Name))
+	}
+	if options.OnForce != nil {
+		options.OnForce(guest)
+	}
+	forceTimeout := options.ForceTimeout
+	if forceTimeout <= 0 {
+		forceTimeout = defaultForceTimeout
+	}
+	return guest.waitForState(ctx, client, forceTimeout, state, false, (*Guest).Poweroff)
+}
@@ -1,11 +1,13 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
 	"path"
+	"time"
 )
 
 type HostBlockDevice struct {
@@ -218,6 +220,15 @@ func (client *Client) GetHost(hostid string) (Host, error) {
 	return host, err
 }
 
+// WaitForHostChange blocks until the host matches a validation function
+func (host Host) WaitForHostChange(ctx context.Context, client *Client, timeout time.Duration, validate func(Host) bool) error {
+	if validate(host) {
+		return nil
+	}
+	_, err := WaitFor(ctx, client, HostTarget(client, host.Hostid), timeout, validate)
+	return err
+}
+
 // GetHostByName requests a host by hostname
 func (client *Client) GetHostByName(name string) (*Host, error) {
 	var hosts, err = client.ListHosts("hostname=" + url.QueryEscape(name))
@@ -229,7 +240,7 @@ func (client *Client) GetHostByName(name string) (*Host, error) {
 			return &host, nil
 		}
 	}
-	return nil, errors.New("Host not found")
+	return nil, notFoundError("Host not found")
 }
 
 // GetHostByIP requests a host by hostname
@@ -243,7 +254,7 @@ func (client *Client) GetHostByIP(ip string) (*Host, error) {
 			return &host, nil
 		}
 	}
-	return nil, errors.New("Host not found")
+	return nil, notFoundError("Host not found")
 }
 
 // UpdateAppliance updates settings from Host.appliance
@@ -0,0 +1,127 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+// errorReader replays a read error after a logged response body was buffered
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// sensitiveKey returns true for json fields and query parameters that must never be logged
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range []string{"password", "secret", "token", "authorization", "key"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			if sensitiveKey(key) {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(val)
+			}
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = redactValue(val)
+		}
+	}
+	return value
+}
+
+// redactBody removes sensitive fields from a json body. Bodies that are not json are omitted
+func redactBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return "[non-json body omitted]"
+	}
+	data, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return "[non-json body omitted]"
+	}
+	return string(data)
+}
+
+// redactURL returns the path and query string of u with sensitive query parameters removed
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	for key := range query {
+		if sensitiveKey(key) {
+			query.Set(key, redacted)
+		}
+	}
+	if len(query) == 0 {
+		return u.Path
+	}
+	return u.Path + "?" + query.Encode()
+}
+
+func (client *Client) logEnabled(ctx context.Context) bool {
+	return client.Logger != nil && client.Logger.Enabled(ctx, slog.LevelDebug)
+}
+
+// logRequest logs a single request attempt. Headers are never logged
+func (client *Client) logRequest(req *http.Request, res *http.Response, err error, attempt int, start time.Time) {
+	ctx := req.Context()
+	if !client.logEnabled(ctx) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("path", redactURL(req.URL)),
+		slog.Duration("latency", time.Since(start)),
+		slog.Int("attempt", attempt),
+	}
+	if client.LogBodies && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(body)
+			body.Close()
+			if len(data) > 0 {
+				attrs = append(attrs, slog.String("request", redactBody(data)))
+			}
+		}
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		client.Logger.LogAttrs(ctx, slog.LevelDebug, "http request failed", attrs...)
+		return
+	}
+	attrs = append(attrs, slog.Int("status", res.StatusCode))
+	if client.LogBodies && strings.HasPrefix(res.Header.Get("Content-Type"), "application/json") {
+		data, readErr := io.ReadAll(res.Body)
+		res.Body.Close()
+		if readErr != nil {
+			res.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), errorReader{readErr}))
+		} else {
+			res.Body = io.NopCloser(bytes.NewReader(data))
+			attrs = append(attrs, slog.String("response", redactBody(data)))
+		}
+	}
+	client.Logger.LogAttrs(ctx, slog.LevelDebug, "http request", attrs...)
+}
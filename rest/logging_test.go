@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	testPassword = "hunter2-password"
+	testToken    = "secret-token-value"
+)
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "auth request", body: `{"username":"admin","password":"` + testPassword + `","realm":"local"}`,
+			want: `{"password":"[REDACTED]","realm":"local","username":"admin"}`},
+		{name: "auth response", body: `{"token":"` + testToken + `"}`, want: `{"token":"[REDACTED]"}`},
+		{name: "nested", body: `[{"name":"realm","bindPassword":"x","tls":{"privateKey":"y"}}]`,
+			want: `[{"bindPassword":"[REDACTED]","name":"realm","tls":{"privateKey":"[REDACTED]"}}]`},
+		{name: "not json", body: "password=" + testPassword, want: "[non-json body omitted]"},
+		{name: "empty", body: "", want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := redactBody([]byte(test.body)); got != test.want {
+				t.Fatalf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	u, err := url.Parse("wss://hive/socket.io/?EIO=3&token=" + testToken + "&transport=websocket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := redactURL(u), "/socket.io/?EIO=3&token=%5BREDACTED%5D&transport=websocket"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+// TestDebugLogRedaction checks the log of a login, an authorized request and a change feed connect
+func TestDebugLogRedaction(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	authorized := make(chan bool, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/auth":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"token":"` + testToken + `"}`))
+		case r.URL.Path == "/api/guests":
+			authorized <- r.Header.Get("Authorization") == "Bearer "+testToken
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[]`))
+		case strings.HasPrefix(r.URL.Path, "/socket.io/"):
+			c, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer c.Close()
+			c.WriteMessage(websocket.TextMessage, []byte(`0{"sid":"abc","upgrades":[],"pingInterval":25000,"pingTimeout":20000}`))
+			for {
+				if _, _, err := c.ReadMessage(); err != nil {
+					return
+				}
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	client := &Client{
+		BaseURL:   srv.URL,
+		Logger:    slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		LogBodies: true,
+	}
+	if err := client.Login("admin", testPassword, "local"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ListGuests(""); err != nil {
+		t.Fatal(err)
+	}
+	if !<-authorized {
+		t.Fatal("the request was not authorized with the token")
+	}
+	feed, err := client.GetChangeFeedWithOptions(context.Background(), ChangeFeedOptions{Table: "guest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed.Close()
+
+	log := buf.String()
+	for _, secret := range []string{testPassword, testToken, "Bearer"} {
+		if strings.Contains(log, secret) {
+			t.Fatalf("log contains %q:\n%s", secret, log)
+		}
+	}
+	for _, want := range []string{"path=/api/auth", "path=/api/guests", "websocket connect", "token=%5BREDACTED%5D", `\"password\":\"[REDACTED]\"`, `\"token\":\"[REDACTED]\"`} {
+		if !strings.Contains(log, want) {
+			t.Fatalf("log does not contain %s:\n%s", want, log)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MigrateAndWait migrates the guest and waits until it is running on the destination host.
+// An empty destinationHostid lets the server choose the host. progress is called with
+// MigrationMetadata.Progress each time it changes and may be nil.
+// On success guest is updated with the migrated record
+func (guest *Guest) MigrateAndWait(ctx context.Context, client *Client, destinationHostid string, timeout time.Duration, progress func(percent int)) error {
+	sourceHostid := guest.Hostid
+	started := false
+	lastProgress := -1
+	loaded := false
+	var initialError *GuestError
+	moved := func(g Guest) bool {
+		if destinationHostid != "" {
+			return g.Hostid == destinationHostid
+		}
+		return g.Hostid != "" && g.Hostid != sourceHostid
+	}
+	var migrateErr error
+	// the watch is opened before the migration is posted so a migration that fails at once is not missed
+	record, err := waitForAfter(ctx, client, GuestTarget(client, guest.Name), timeout, func(ctx context.Context) error {
+		migrateErr = guest.migrate(ctx, client, destinationHostid)
+		return migrateErr
+	}, func(g Guest) bool {
+		if !loaded {
+			// the first record is the guest before the migration was posted
+			loaded = true
+			initialError = g.Error
+		}
+		if g.MigrationProcessing {
+			// metadata left by an earlier migration is ignored until this one is processing
+			started = true
+			if g.MigrationMetadata != nil && g.MigrationMetadata.Progress != lastProgress {
+				lastProgress = g.MigrationMetadata.Progress
+				if progress != nil {
+					progress(lastProgress)
+				}
+			}
+			return false
+		}
+		// a new error on the source host means the migration failed, possibly before it was processing
+		failed := g.Hostid == sourceHostid && g.Error != nil && (initialError == nil || *g.Error != *initialError)
+		// the migration either finished on the destination or stopped without moving the guest
+		return moved(g) || (started && g.Hostid == sourceHostid) || failed
+	})
+	if migrateErr != nil {
+		return migrateErr
+	}
+	if err != nil {
+		return fmt.Errorf("guest %s migration did not finish: %w", guest.Name, err)
+	}
+	if !moved(record) {
+		if record.Error != nil && record.Error.Message != "" {
+			return fmt.Errorf("guest %s migration failed: %s", guest.Name, record.Error.Message)
+		}
+		return fmt.Errorf("guest %s migration failed", guest.Name)
+	}
+	if progress != nil && lastProgress != 100 {
+		progress(100)
+	}
+	*guest = record
+	return nil
+}
+
+// EvacuateOptions configures Host.Evacuate
+type EvacuateOptions struct {
+	// Parallel is the number of guests migrated at once. Defaults to 1
+	Parallel int
+	// Placement picks the least loaded available host allowed by the guest pool affinity for each guest.
+	// Without it the server chooses the destination
+	Placement bool
+	// Timeout for each migration. 0 waits until ctx is done
+	Timeout time.Duration
+	// Maintenance puts the host into maintenance once every guest has been migrated
+	Maintenance bool
+	// Progress is called as each migration finishes and may be nil
+	Progress func(result GuestResult)
+}
+
+// EvacuationError is returned by Host.Evacuate when guests could not be migrated
+type EvacuationError struct {
+	Failed []GuestResult
+}
+
+func (e *EvacuationError) Error() string {
+	return fmt.Sprintf("%d guests could not be migrated", len(e.Failed))
+}
+
+// hostLoad tracks the guests placed on a host during an evacuation
+type hostLoad struct {
+	host   Host
+	memory int
+	guests int
+}
+
+// hostPlacer picks destinations for evacuated guests
+type hostPlacer struct {
+	client *Client
+	mu     sync.Mutex
+	loads  []*hostLoad
+	pools  map[string]*Pool
+}
+
+func newHostPlacer(client *Client, source string) (*hostPlacer, error) {
+	hosts, err := client.ListHosts("")
+	if err != nil {
+		return nil, err
+	}
+	guests, err := client.ListGuests("")
+	if err != nil {
+		return nil, err
+	}
+	placer := &hostPlacer{client: client, pools: map[string]*Pool{}}
+	byID := map[string]*hostLoad{}
+	for _, host := range hosts {
+		if host.Hostid == source || host.State != "available" {
+			continue
+		}
+		load := &hostLoad{host: host}
+		byID[host.Hostid] = load
+		placer.loads = append(placer.loads, load)
+	}
+	for _, guest := range guests {
+		if load, ok := byID[guest.Hostid]; ok {
+			load.memory += guest.Memory
+			load.guests++
+		}
+	}
+	return placer, nil
+}
+
+// allowedHosts returns the hosts the guest pool affinity allows, nil allows every host
+func (placer *hostPlacer) allowedHosts(guest Guest) (map[string]bool, error) {
+	if guest.PoolID == "" {
+		return nil, nil
+	}
+	placer.mu.Lock()
+	pool, ok := placer.pools[guest.PoolID]
+	placer.mu.Unlock()
+	if !ok {
+		var err error
+		pool, err = placer.client.GetPool(guest.PoolID)
+		if err != nil && !IsNotFound(err) {
+			return nil, err
+		}
+		placer.mu.Lock()
+		placer.pools[guest.PoolID] = pool
+		placer.mu.Unlock()
+	}
+	if pool == nil || pool.PoolAffinity == nil || len(pool.PoolAffinity.AllowedHostIDs) == 0 {
+		return nil, nil
+	}
+	allowed := map[string]bool{}
+	for _, hostid := range pool.PoolAffinity.AllowedHostIDs {
+		allowed[hostid] = true
+	}
+	return allowed, nil
+}
+
+// place reserves the least loaded allowed host for guest and returns its hostid
+func (placer *hostPlacer) place(guest Guest) (string, error) {
+	allowed, err := placer.allowedHosts(guest)
+	if err != nil {
+		return "", err
+	}
+	placer.mu.Lock()
+	defer placer.mu.Unlock()
+	candidates := []*hostLoad{}
+	for _, load := range placer.loads {
+		if allowed == nil || allowed[load.host.Hostid] {
+			candidates = append(candidates, load)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", errors.New("no available host allowed by the pool affinity")
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.memory != b.memory {
+			return a.memory < b.memory
+		}
+		if a.guests != b.guests {
+			return a.guests < b.guests
+		}
+		return a.host.Hostname < b.host.Hostname
+	})
+	best := candidates[0]
+	best.memory += guest.Memory
+	best.guests++
+	return best.host.Hostid, nil
+}
+
+// Evacuate migrates every running guest off the host. The results are returned in the order the guests were listed.
+// If any guest could not be migrated an *EvacuationError is returned and the host is not put into maintenance
+func (host *Host) Evacuate(ctx context.Context, client *Client, options EvacuateOptions) ([]GuestResult, error) {
+	if host.Hostid == "" {
+		return nil, errors.New("hostid cannot be empty")
+	}
+	listed, err := client.ListGuests("hostid=" + host.Hostid)
+	if err != nil {
+		return nil, err
+	}
+	guests := []Guest{}
+	for _, guest := range listed {
+		if guest.Hostid == host.Hostid && !guest.External && guest.GuestState != GuestStateStopped {
+			guests = append(guests, guest)
+		}
+	}
+
+	var placer *hostPlacer
+	if options.Placement && len(guests) > 0 {
+		if placer, err = newHostPlacer(client, host.Hostid); err != nil {
+			return nil, err
+		}
+	}
+	var progressMu sync.Mutex
+	results := client.BulkGuestOperation(ctx, guests, options.Parallel, func(ctx context.Context, guest *Guest) error {
+		destination := ""
+		if placer != nil {
+			var err error
+			if destination, err = placer.place(*guest); err != nil {
+				return err
+			}
+		}
+		err := guest.MigrateAndWait(ctx, client, destination, options.Timeout, nil)
+		if options.Progress != nil {
+			progressMu.Lock()
+			options.Progress(GuestResult{Guest: *guest, Err: err})
+			progressMu.Unlock()
+		}
+		return err
+	})
+
+	failed := []GuestResult{}
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	if len(failed) > 0 {
+		return results, &EvacuationError{Failed: failed}
+	}
+	if options.Maintenance {
+		task, err := host.SetState(client, "maintenance")
+		if err != nil {
+			return results, err
+		}
+		if task, err = task.WaitForTaskWithContext(ctx, client, false); err != nil {
+			return results, err
+		}
+		if task.State == "failed" {
+			return results, fmt.Errorf("failed to put host %s into maintenance: %s", host.Hostname, task.Message)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,49 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestMigrateAndWaitTimeoutCancelsPost(t *testing.T) {
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/guest/vm1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"vm1","hostid":"h1"}`))
+	})
+	mux.HandleFunc("/api/guest/vm1/migrate", func(w http.ResponseWriter, r *http.Request) {
+		// the migration request hangs until the test ends
+		<-release
+	})
+	mux.HandleFunc("/socket.io/", fakeSocketHandler(func(c *websocket.Conn) {
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	defer close(release)
+	client := &Client{BaseURL: srv.URL}
+	guest := Guest{Name: "vm1", Hostid: "h1"}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- guest.MigrateAndWait(context.Background(), client, "h2", 200*time.Millisecond, nil)
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("migration returned no error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the migration request was not cancelled by the timeout")
+	}
+}
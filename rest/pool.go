@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -150,7 +151,7 @@ func (client *Client) GetPoolByName(name string) (*Pool, error) {
 			return &pool, nil
 		}
 	}
-	return nil, errors.New("Pool not found")
+	return nil, notFoundError("Pool not found")
 }
 
 // Create creates a new pool
@@ -195,44 +196,108 @@ func (pool *Pool) Refresh(client *Client) error {
 
 // WaitForPool waits for a pool to reach the desired state
 func (pool Pool) WaitForPool(client *Client, targetState string, timeout time.Duration) error {
-	return pool.WaitForPoolWithContext(context.Background(), client, targetState, timeout)
+	return pool.WaitForPoolWithContext(client.getContext(), client, targetState, timeout)
 }
 
 // WaitForPoolWithContext waits for a pool to reach the desired state with a context
 func (pool Pool) WaitForPoolWithContext(ctx context.Context, client *Client, targetState string, timeout time.Duration) error {
-	if pool.State == targetState {
+	return pool.WaitForPoolChange(ctx, client, timeout, func(pool Pool) bool {
+		return pool.State == targetState
+	})
+}
+
+// WaitForPoolChange blocks until the pool matches a validation function
+func (pool Pool) WaitForPoolChange(ctx context.Context, client *Client, timeout time.Duration, validate func(Pool) bool) error {
+	if validate(pool) {
 		return nil
 	}
-	newVal := Pool{}
-	feed, err := client.GetChangeFeedWithContext(ctx, "pool", map[string]string{"id": pool.ID}, false)
-	if err != nil {
-		return err
+	_, err := WaitFor(ctx, client, PoolTarget(client, pool.ID), timeout, validate)
+	return err
+}
+
+// PoolGuestStatus summarizes the guests of a pool while waiting for them
+type PoolGuestStatus struct {
+	Guests []Guest
+	// Ready is the number of guests in the target state
+	Ready int
+	// Failed contains the guests reporting a GuestError
+	Failed []Guest
+}
+
+// PoolGuestsError is returned by WaitForGuests when guests in the pool report an error
+type PoolGuestsError struct {
+	Guests []Guest
+}
+
+func (e *PoolGuestsError) Error() string {
+	names := make([]string, 0, len(e.Guests))
+	for _, guest := range e.Guests {
+		names = append(names, fmt.Sprintf("%s (%s: %s)", guest.Name, guest.Error.Code, guest.Error.Message))
+	}
+	return fmt.Sprintf("%d guests failed: %s", len(e.Guests), strings.Join(names, ", "))
+}
+
+// PoolWaitOptions configures WaitForGuests
+type PoolWaitOptions struct {
+	// State is the guest state to wait for. Defaults to ready
+	State string
+	// MinReady is the number of guests that must reach State. 0 waits for every guest in the pool, and for at
+	// least the minimum density of the pool so a pool whose guests are still being created is not done.
+	// An empty pool with a minimum density of 0 is done at once
+	MinReady int
+	// IgnoreErrors keeps waiting when guests report a GuestError instead of returning a PoolGuestsError
+	IgnoreErrors bool
+	// Progress is called with the current status whenever the guests change
+	Progress func(PoolGuestStatus)
+}
+
+// NewPoolGuestStatus counts the guests in state and the guests reporting an error
+func NewPoolGuestStatus(guests []Guest, state string) PoolGuestStatus {
+	status := PoolGuestStatus{Guests: guests}
+	for _, guest := range guests {
+		if guest.Error != nil {
+			status.Failed = append(status.Failed, guest)
+		}
+		if strings.EqualFold(guest.GuestState, state) {
+			status.Ready++
+		}
 	}
-	timer := time.NewTimer(timeout)
-	if timeout <= 0 && !timer.Stop() {
-		<-timer.C
+	return status
+}
+
+// WaitForGuests blocks until the guests in the pool reach options.State and returns their final status
+func (pool Pool) WaitForGuests(ctx context.Context, client *Client, timeout time.Duration, options PoolWaitOptions) (PoolGuestStatus, error) {
+	if pool.ID == "" {
+		return PoolGuestStatus{}, errors.New("invalid pool")
 	}
-	for {
-		select {
-		case <-timer.C:
-			return fmt.Errorf("timed out")
-		case msg := <-feed.Data:
-			if msg.Error != nil {
-				feed.Close()
-				return msg.Error
-			}
-			err = json.Unmarshal(msg.NewValue, &newVal)
-			if err != nil {
-				err = fmt.Errorf("error with json unmarshal: %v", err)
-				feed.Close()
-				return err
-			}
-			if newVal.State == targetState {
-				feed.Close()
-				return nil
-			}
+	if options.State == "" {
+		options.State = "ready"
+	}
+	minGuests := 0
+	if len(pool.Density) > 0 {
+		minGuests = pool.Density[0]
+	}
+	var status PoolGuestStatus
+	_, err := WaitForAll(ctx, client, PoolGuestsTarget(client, pool.ID), timeout, func(guests []Guest) bool {
+		status = NewPoolGuestStatus(guests, options.State)
+		if options.Progress != nil {
+			options.Progress(status)
+		}
+		if len(status.Failed) > 0 && !options.IgnoreErrors {
+			return true
 		}
+		if options.MinReady > 0 {
+			return status.Ready >= options.MinReady
+		}
+		return len(guests) >= minGuests && status.Ready == len(guests)
+	})
+	if err != nil {
+		return status, err
+	}
+	if len(status.Failed) > 0 && !options.IgnoreErrors {
+		return status, &PoolGuestsError{Guests: status.Failed}
 	}
+	return status, nil
 }
 
 // Assign adds a user or group assignment for a standalone pool
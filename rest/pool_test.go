@@ -0,0 +1,67 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newPoolGuestsServer serves an empty guest list and sends changes to the guest change feed
+func newPoolGuestsServer(t *testing.T, changes ...string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/guests", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	})
+	mux.HandleFunc("/socket.io/", fakeSocketHandler(func(c *websocket.Conn) {
+		if _, _, err := c.ReadMessage(); err != nil {
+			return
+		}
+		for _, change := range changes {
+			if err := c.WriteMessage(websocket.TextMessage, []byte(change)); err != nil {
+				return
+			}
+		}
+		c.ReadMessage()
+	}))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWaitForGuestsEmptyPool(t *testing.T) {
+	client := &Client{BaseURL: newPoolGuestsServer(t).URL}
+	pool := Pool{ID: "p1", Density: []int{0, 0}}
+	done := make(chan error, 1)
+	go func() {
+		_, err := pool.WaitForGuests(context.Background(), client, time.Minute, PoolWaitOptions{})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting for an empty pool did not return")
+	}
+}
+
+func TestWaitForGuestsWaitsForMinimumDensity(t *testing.T) {
+	change := `42["query:change",{"table":"guest"},{"old_val":null,"new_val":{"name":"g1","poolId":"p1","guestState":"ready"}}]`
+	client := &Client{BaseURL: newPoolGuestsServer(t, change).URL}
+	// the pool is empty until the guest created by a refresh is ready
+	pool := Pool{ID: "p1", Density: []int{1, 1}}
+	status, err := pool.WaitForGuests(context.Background(), client, 5*time.Second, PoolWaitOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Ready != 1 || len(status.Guests) != 1 {
+		t.Fatalf("got %d/%d guests ready, want 1/1", status.Ready, len(status.Guests))
+	}
+}
@@ -161,5 +161,5 @@ func (client *Client) GetProfileByName(name string) (*Profile, error) {
 			return &profile, nil
 		}
 	}
-	return nil, errors.New("Profile not found")
+	return nil, notFoundError("Profile not found")
 }
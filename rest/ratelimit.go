@@ -0,0 +1,84 @@
+package rest
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket allowing rate requests per second with bursts of up to burst requests
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// wait blocks until a token is available or ctx is done
+func (limiter *rateLimiter) wait(ctx context.Context) error {
+	for {
+		limiter.mu.Lock()
+		now := time.Now()
+		limiter.tokens = min(limiter.burst, limiter.tokens+now.Sub(limiter.last).Seconds()*limiter.rate)
+		limiter.last = now
+		if limiter.tokens >= 1 {
+			limiter.tokens--
+			limiter.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - limiter.tokens) / limiter.rate * float64(time.Second))
+		limiter.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// initLimits creates the rate limiter and in flight semaphore from the client settings on first use
+func (client *Client) initLimits() {
+	client.limitsOnce.Do(func() {
+		if client.RateLimit > 0 {
+			client.limiter = newRateLimiter(client.RateLimit, client.RateBurst)
+		}
+		if client.MaxInFlight > 0 {
+			client.inFlight = make(chan struct{}, client.MaxInFlight)
+		}
+	})
+}
+
+// waitRateLimit blocks until the rate limiter allows another request
+func (client *Client) waitRateLimit(ctx context.Context) error {
+	client.initLimits()
+	if client.limiter == nil {
+		return nil
+	}
+	return client.limiter.wait(ctx)
+}
+
+// acquire blocks until fewer than MaxInFlight requests are running and returns a function to release the slot
+func (client *Client) acquire(ctx context.Context) (func(), error) {
+	client.initLimits()
+	if client.inFlight == nil {
+		return func() {}, nil
+	}
+	select {
+	case client.inFlight <- struct{}{}:
+		var once sync.Once
+		return func() { once.Do(func() { <-client.inFlight }) }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,137 @@
+package rest
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how requests that fail with a transient error are retried.
+// Set Client.Retry to enable retries, a nil policy sends each request once.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first request
+	MaxAttempts int
+	// MinBackoff is the delay before the first retry
+	MinBackoff time.Duration
+	// MaxBackoff is the upper bound on the delay between retries
+	MaxBackoff time.Duration
+	// RetryStatusCodes lists the http status codes that will be retried
+	RetryStatusCodes []int
+	// RetryNonIdempotent allows POST and PATCH requests to be retried
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns a RetryPolicy suitable for riding out service restarts
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:      5,
+		MinBackoff:       500 * time.Millisecond,
+		MaxBackoff:       30 * time.Second,
+		RetryStatusCodes: []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	}
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// shouldRetry reports whether a request should be attempted again after res, err
+func (policy *RetryPolicy) shouldRetry(ctx context.Context, req *http.Request, res *http.Response, err error, attempt int) bool {
+	if policy == nil || attempt >= policy.MaxAttempts || ctx.Err() != nil {
+		return false
+	}
+	if !isIdempotent(req.Method) && !policy.RetryNonIdempotent {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		var certErr *tls.CertificateVerificationError
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.As(err, &certErr) {
+			return false
+		}
+		return true
+	}
+	return slices.Contains(policy.RetryStatusCodes, res.StatusCode)
+}
+
+// backoff returns the delay before the next attempt using exponential backoff with full jitter
+func (policy *RetryPolicy) backoff(attempt int, res *http.Response) time.Duration {
+	delay := policy.MinBackoff
+	for i := 1; i < attempt && (policy.MaxBackoff <= 0 || delay < policy.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if policy.MaxBackoff > 0 && delay > policy.MaxBackoff {
+		delay = policy.MaxBackoff
+	}
+	if delay > policy.MinBackoff {
+		delay = policy.MinBackoff + rand.N(delay-policy.MinBackoff)
+	}
+	if retryAfter := parseRetryAfter(res); retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay
+}
+
+func parseRetryAfter(res *http.Response) time.Duration {
+	if res == nil {
+		return 0
+	}
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+// doWithRetry sends req and retries it according to the client's RetryPolicy
+func (client *Client) doWithRetry(httpClient *http.Client, req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		if err := client.waitRateLimit(ctx); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		res, err := httpClient.Do(req)
+		client.logRequest(req, res, err, attempt, start)
+		if !client.Retry.shouldRetry(ctx, req, res, err, attempt) {
+			return res, err
+		}
+		delay := client.Retry.backoff(attempt, res)
+		if res != nil {
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// engine.io packet types
+const (
+	engineOpen    byte = '0'
+	engineClose   byte = '1'
+	enginePing    byte = '2'
+	enginePong    byte = '3'
+	engineMessage byte = '4'
+	engineUpgrade byte = '5'
+	engineNoop    byte = '6'
+)
+
+// socket.io packet types carried in engine.io message packets
+const (
+	socketConnect      byte = '0'
+	socketDisconnect   byte = '1'
+	socketEvent        byte = '2'
+	socketAck          byte = '3'
+	socketConnectError byte = '4'
+	socketBinaryEvent  byte = '5'
+	socketBinaryAck    byte = '6'
+)
+
+const defaultNamespace = "/"
+
+// engineIOVersion is the engine.io protocol requested when dialing. Only version 3 is supported: the client
+// pings the server and the default namespace is connected without a connect packet. A version 4 server
+// needs allowEIO3 enabled
+const engineIOVersion = 3
+
+// DisconnectReason describes why the server or transport ended a change feed connection.
+// The values match the reasons reported by the socket.io javascript client.
+type DisconnectReason string
+
+// DisconnectReasons reported in a DisconnectError
+const (
+	DisconnectServer         DisconnectReason = "io server disconnect"
+	DisconnectTransportClose DisconnectReason = "transport close"
+	DisconnectTransportError DisconnectReason = "transport error"
+	DisconnectPingTimeout    DisconnectReason = "ping timeout"
+	DisconnectConnectError   DisconnectReason = "connect error"
+	DisconnectParseError     DisconnectReason = "parse error"
+)
+
+// DisconnectError is returned by change feeds when the socket.io connection ends
+type DisconnectError struct {
+	Reason    DisconnectReason
+	Namespace string
+	// Message is the message sent by the server with a connect error
+	Message string
+	Err     error
+}
+
+func (e *DisconnectError) Error() string {
+	msg := "change feed disconnected: " + string(e.Reason)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	} else if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *DisconnectError) Unwrap() error {
+	return e.Err
+}
+
+// IsDisconnect returns true if err is a DisconnectError with one of reasons, or any DisconnectError when no reasons are given
+func IsDisconnect(err error, reasons ...DisconnectReason) bool {
+	var disconnectErr *DisconnectError
+	if !errors.As(err, &disconnectErr) {
+		return false
+	}
+	if len(reasons) == 0 {
+		return true
+	}
+	for _, reason := range reasons {
+		if disconnectErr.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// engineHandshake is the payload of the engine.io open packet
+type engineHandshake struct {
+	SID          string   `json:"sid"`
+	Upgrades     []string `json:"upgrades"`
+	PingInterval int      `json:"pingInterval"`
+	PingTimeout  int      `json:"pingTimeout"`
+	MaxPayload   int      `json:"maxPayload"`
+}
+
+// socketPacket is a decoded socket.io packet. AckID is -1 when the packet has no ack id
+type socketPacket struct {
+	Type      byte
+	Namespace string
+	AckID     int
+	Data      json.RawMessage
+}
+
+// decodeEnginePacket splits an engine.io websocket frame into its type and payload
+func decodeEnginePacket(frame []byte) (byte, []byte, error) {
+	if len(frame) == 0 {
+		return 0, nil, errors.New("empty engine.io packet")
+	}
+	if frame[0] < engineOpen || frame[0] > engineNoop {
+		return 0, nil, fmt.Errorf("unknown engine.io packet type %q", frame[0])
+	}
+	return frame[0], frame[1:], nil
+}
+
+// decodeSocketPacket decodes the payload of an engine.io message packet.
+// The format is <type>[<attachments>-][<namespace>,][<ack id>][json data]
+func decodeSocketPacket(payload []byte) (socketPacket, error) {
+	packet := socketPacket{Namespace: defaultNamespace, AckID: -1}
+	if len(payload) == 0 {
+		return packet, errors.New("empty socket.io packet")
+	}
+	packet.Type = payload[0]
+	if packet.Type < socketConnect || packet.Type > socketBinaryAck {
+		return packet, fmt.Errorf("unknown socket.io packet type %q", packet.Type)
+	}
+	rest := payload[1:]
+	if packet.Type == socketBinaryEvent || packet.Type == socketBinaryAck {
+		i := bytes.IndexByte(rest, '-')
+		if i < 0 {
+			return packet, errors.New("invalid socket.io binary packet")
+		}
+		rest = rest[i+1:]
+	}
+	if len(rest) > 0 && rest[0] == '/' {
+		i := bytes.IndexByte(rest, ',')
+		if i < 0 {
+			packet.Namespace = string(rest)
+			return packet, nil
+		}
+		packet.Namespace = string(rest[:i])
+		rest = rest[i+1:]
+	}
+	i := 0
+	for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+		i++
+	}
+	if i > 0 {
+		id, err := strconv.Atoi(string(rest[:i]))
+		if err != nil {
+			return packet, fmt.Errorf("invalid socket.io ack id: %w", err)
+		}
+		packet.AckID = id
+		rest = rest[i:]
+	}
+	if len(rest) > 0 {
+		if !json.Valid(rest) {
+			return packet, errors.New("invalid socket.io packet data")
+		}
+		packet.Data = rest
+	}
+	return packet, nil
+}
+
+// encodeSocketPacket encodes packet as an engine.io message frame. Binary attachments are not supported
+func encodeSocketPacket(packet socketPacket) []byte {
+	frame := []byte{engineMessage, packet.Type}
+	if packet.Namespace != "" && packet.Namespace != defaultNamespace {
+		frame = append(frame, packet.Namespace...)
+		frame = append(frame, ',')
+	}
+	if packet.AckID >= 0 {
+		frame = strconv.AppendInt(frame, int64(packet.AckID), 10)
+	}
+	return append(frame, packet.Data...)
+}
+
+// encodeEvent encodes a socket.io event packet with the event name followed by args
+func encodeEvent(namespace string, ackID int, event string, args ...interface{}) ([]byte, error) {
+	data, err := json.Marshal(append([]interface{}{event}, args...))
+	if err != nil {
+		return nil, err
+	}
+	return encodeSocketPacket(socketPacket{Type: socketEvent, Namespace: namespace, AckID: ackID, Data: data}), nil
+}
+
+// socketConn speaks engine.io v3 and socket.io over a websocket connected to the default namespace.
+// The client pings the server, pings sent by the server are still answered with pongs.
+type socketConn struct {
+	conn      *websocket.Conn
+	namespace string
+	handshake engineHandshake
+	writeMu   sync.Mutex
+}
+
+// newSocketConn reads the engine.io open packet from conn
+func newSocketConn(conn *websocket.Conn, timeout time.Duration) (*socketConn, error) {
+	namespace := defaultNamespace
+	c := &socketConn{conn: conn, namespace: namespace}
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	_, frame, err := conn.ReadMessage()
+	if err != nil {
+		return nil, c.transportError(err)
+	}
+	typ, payload, err := decodeEnginePacket(frame)
+	if err != nil {
+		return nil, &DisconnectError{Reason: DisconnectParseError, Namespace: namespace, Err: err}
+	}
+	if typ != engineOpen {
+		return nil, &DisconnectError{Reason: DisconnectParseError, Namespace: namespace, Err: fmt.Errorf("expected engine.io open packet, got %q", typ)}
+	}
+	if err = json.Unmarshal(payload, &c.handshake); err != nil {
+		return nil, &DisconnectError{Reason: DisconnectParseError, Namespace: namespace, Err: err}
+	}
+	return c, nil
+}
+
+// pingInterval returns the interval from the open packet, defaulting to 25s
+func (c *socketConn) pingInterval() time.Duration {
+	if c.handshake.PingInterval > 0 {
+		return time.Duration(c.handshake.PingInterval) * time.Millisecond
+	}
+	return 25 * time.Second
+}
+
+// readTimeout is how long the connection may stay silent before it is considered dead
+func (c *socketConn) readTimeout() time.Duration {
+	timeout := 20 * time.Second
+	if c.handshake.PingTimeout > 0 {
+		timeout = time.Duration(c.handshake.PingTimeout) * time.Millisecond
+	}
+	return c.pingInterval() + timeout
+}
+
+func (c *socketConn) write(frame []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, frame)
+}
+
+// emit sends an event without an ack id
+func (c *socketConn) emit(event string, args ...interface{}) error {
+	frame, err := encodeEvent(c.namespace, -1, event, args...)
+	if err != nil {
+		return err
+	}
+	return c.write(frame)
+}
+
+// ack answers an event that requested an acknowledgement
+func (c *socketConn) ack(id int, args ...interface{}) error {
+	data, err := json.Marshal(append([]interface{}{}, args...))
+	if err != nil {
+		return err
+	}
+	return c.write(encodeSocketPacket(socketPacket{Type: socketAck, Namespace: c.namespace, AckID: id, Data: data}))
+}
+
+// ping sends an engine.io ping
+func (c *socketConn) ping() error {
+	return c.write([]byte{enginePing})
+}
+
+// closeGracefully sends a websocket close frame
+func (c *socketConn) closeGracefully(deadline time.Time) error {
+	return c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), deadline)
+}
+
+func (c *socketConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *socketConn) transportError(err error) error {
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return &DisconnectError{Reason: DisconnectPingTimeout, Namespace: c.namespace, Err: err}
+	case websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway):
+		return &DisconnectError{Reason: DisconnectTransportClose, Namespace: c.namespace, Err: err}
+	default:
+		return &DisconnectError{Reason: DisconnectTransportError, Namespace: c.namespace, Err: err}
+	}
+}
+
+// readPacket returns the next socket.io packet for the namespace. Engine.io pings and events requesting
+// an ack are answered, and disconnect and connect error packets are returned as a DisconnectError
+func (c *socketConn) readPacket() (socketPacket, error) {
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(c.readTimeout()))
+		_, frame, err := c.conn.ReadMessage()
+		if err != nil {
+			return socketPacket{}, c.transportError(err)
+		}
+		typ, payload, err := decodeEnginePacket(frame)
+		if err != nil {
+			return socketPacket{}, &DisconnectError{Reason: DisconnectParseError, Namespace: c.namespace, Err: err}
+		}
+		switch typ {
+		case enginePing:
+			if err = c.write(append([]byte{enginePong}, payload...)); err != nil {
+				return socketPacket{}, c.transportError(err)
+			}
+			continue
+		case engineClose:
+			return socketPacket{}, &DisconnectError{Reason: DisconnectTransportClose, Namespace: c.namespace}
+		case engineMessage:
+		default:
+			continue
+		}
+		packet, err := decodeSocketPacket(payload)
+		if err != nil {
+			return packet, &DisconnectError{Reason: DisconnectParseError, Namespace: c.namespace, Err: err}
+		}
+		if packet.Namespace != c.namespace {
+			continue
+		}
+		switch packet.Type {
+		case socketDisconnect:
+			return packet, &DisconnectError{Reason: DisconnectServer, Namespace: c.namespace}
+		case socketConnectError:
+			return packet, &DisconnectError{Reason: DisconnectConnectError, Namespace: c.namespace, Message: connectErrorMessage(packet.Data)}
+		case socketEvent:
+			if packet.AckID >= 0 {
+				if err = c.ack(packet.AckID); err != nil {
+					return packet, c.transportError(err)
+				}
+			}
+		}
+		return packet, nil
+	}
+}
+
+// connectErrorMessage extracts the message from a connect error, either {"message": "..."} or a plain string
+func connectErrorMessage(data json.RawMessage) string {
+	var body struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Message != "" {
+		return body.Message
+	}
+	var message string
+	if json.Unmarshal(data, &message) == nil {
+		return message
+	}
+	return string(data)
+}
@@ -0,0 +1,259 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDecodeEnginePacket(t *testing.T) {
+	tests := []struct {
+		name    string
+		frame   string
+		typ     byte
+		payload string
+		wantErr bool
+	}{
+		{name: "open", frame: `0{"sid":"lv_VI97HAXpY6yYWAAAC","upgrades":[],"pingInterval":25000,"pingTimeout":20000,"maxPayload":1000000}`,
+			typ: engineOpen, payload: `{"sid":"lv_VI97HAXpY6yYWAAAC","upgrades":[],"pingInterval":25000,"pingTimeout":20000,"maxPayload":1000000}`},
+		{name: "ping", frame: "2", typ: enginePing},
+		{name: "ping probe", frame: "2probe", typ: enginePing, payload: "probe"},
+		{name: "pong", frame: "3", typ: enginePong},
+		{name: "close", frame: "1", typ: engineClose},
+		{name: "message", frame: `42["query:change",{}]`, typ: engineMessage, payload: `2["query:change",{}]`},
+		{name: "noop", frame: "6", typ: engineNoop},
+		{name: "empty", frame: "", wantErr: true},
+		{name: "unknown type", frame: "9", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			typ, payload, err := decodeEnginePacket([]byte(test.frame))
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got type %q", typ)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if typ != test.typ || string(payload) != test.payload {
+				t.Fatalf("got %q %q, want %q %q", typ, payload, test.typ, test.payload)
+			}
+		})
+	}
+}
+
+func TestDecodeSocketPacket(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    socketPacket
+		wantErr bool
+	}{
+		{name: "connect", payload: "0", want: socketPacket{Type: socketConnect, Namespace: "/", AckID: -1}},
+		{name: "connect ack", payload: `0{"sid":"def"}`, want: socketPacket{Type: socketConnect, Namespace: "/", AckID: -1, Data: json.RawMessage(`{"sid":"def"}`)}},
+		{name: "connect namespace", payload: "0/admin,", want: socketPacket{Type: socketConnect, Namespace: "/admin", AckID: -1}},
+		{name: "disconnect", payload: "1", want: socketPacket{Type: socketDisconnect, Namespace: "/", AckID: -1}},
+		{name: "disconnect namespace without comma", payload: "1/admin", want: socketPacket{Type: socketDisconnect, Namespace: "/admin", AckID: -1}},
+		{name: "event", payload: `2["query:change",{"table":"guest"},{"old_val":null,"new_val":{"name":"guest1"}}]`,
+			want: socketPacket{Type: socketEvent, Namespace: "/", AckID: -1, Data: json.RawMessage(`["query:change",{"table":"guest"},{"old_val":null,"new_val":{"name":"guest1"}}]`)}},
+		{name: "namespaced event with ack id", payload: `2/admin,12["event",{"a":1}]`,
+			want: socketPacket{Type: socketEvent, Namespace: "/admin", AckID: 12, Data: json.RawMessage(`["event",{"a":1}]`)}},
+		{name: "ack", payload: `37["ok"]`, want: socketPacket{Type: socketAck, Namespace: "/", AckID: 7, Data: json.RawMessage(`["ok"]`)}},
+		{name: "connect error", payload: `4{"message":"not authorized"}`,
+			want: socketPacket{Type: socketConnectError, Namespace: "/", AckID: -1, Data: json.RawMessage(`{"message":"not authorized"}`)}},
+		{name: "binary event", payload: `51-["upload",{"_placeholder":true,"num":0}]`,
+			want: socketPacket{Type: socketBinaryEvent, Namespace: "/", AckID: -1, Data: json.RawMessage(`["upload",{"_placeholder":true,"num":0}]`)}},
+		{name: "binary ack", payload: `62-/admin,5[{"_placeholder":true,"num":0},{"_placeholder":true,"num":1}]`,
+			want: socketPacket{Type: socketBinaryAck, Namespace: "/admin", AckID: 5, Data: json.RawMessage(`[{"_placeholder":true,"num":0},{"_placeholder":true,"num":1}]`)}},
+		{name: "empty", payload: "", wantErr: true},
+		{name: "unknown type", payload: "9", wantErr: true},
+		{name: "binary without attachments", payload: `5["upload"]`, wantErr: true},
+		{name: "invalid json", payload: `2["query:change"`, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			packet, err := decodeSocketPacket([]byte(test.payload))
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", packet)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if packet.Type != test.want.Type || packet.Namespace != test.want.Namespace || packet.AckID != test.want.AckID || string(packet.Data) != string(test.want.Data) {
+				t.Fatalf("got %+v, want %+v", packet, test.want)
+			}
+		})
+	}
+}
+
+func TestEncodeSocketPacket(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet socketPacket
+		frame  string
+	}{
+		{name: "connect", packet: socketPacket{Type: socketConnect, AckID: -1}, frame: "40"},
+		{name: "connect namespace", packet: socketPacket{Type: socketConnect, Namespace: "/admin", AckID: -1}, frame: "40/admin,"},
+		{name: "disconnect", packet: socketPacket{Type: socketDisconnect, Namespace: "/", AckID: -1}, frame: "41"},
+		{name: "event", packet: socketPacket{Type: socketEvent, Namespace: "/", AckID: -1, Data: json.RawMessage(`["query:change:register",{"table":"guest"}]`)},
+			frame: `42["query:change:register",{"table":"guest"}]`},
+		{name: "namespaced event with ack id", packet: socketPacket{Type: socketEvent, Namespace: "/admin", AckID: 12, Data: json.RawMessage(`["event"]`)},
+			frame: `42/admin,12["event"]`},
+		{name: "ack", packet: socketPacket{Type: socketAck, Namespace: "/", AckID: 7, Data: json.RawMessage(`[]`)}, frame: "437[]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			frame := encodeSocketPacket(test.packet)
+			if string(frame) != test.frame {
+				t.Fatalf("got %s, want %s", frame, test.frame)
+			}
+			// the frame decodes back into the same packet
+			typ, payload, err := decodeEnginePacket(frame)
+			if err != nil || typ != engineMessage {
+				t.Fatalf("decoding %s: %q %v", frame, typ, err)
+			}
+			packet, err := decodeSocketPacket(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			namespace := test.packet.Namespace
+			if namespace == "" {
+				namespace = defaultNamespace
+			}
+			if packet.Type != test.packet.Type || packet.Namespace != namespace || packet.AckID != test.packet.AckID || string(packet.Data) != string(test.packet.Data) {
+				t.Fatalf("round trip got %+v, want %+v", packet, test.packet)
+			}
+		})
+	}
+}
+
+// newSocketPair returns a websocket connected to a server that sends frames and then sends every frame
+// received from the client to replies
+func newSocketPair(t *testing.T, frames []string) (*websocket.Conn, <-chan string) {
+	t.Helper()
+	replies := make(chan string, 10)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		for _, frame := range frames {
+			if err := c.WriteMessage(websocket.TextMessage, []byte(frame)); err != nil {
+				return
+			}
+		}
+		for {
+			_, frame, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			replies <- string(frame)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, replies
+}
+
+func TestReadPacket(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		frames    []string
+		want      socketPacket
+		reason    DisconnectReason
+		message   string
+		replies   []string
+	}{
+		{name: "server ping is answered", frames: []string{"2", `42["query:change",{}]`},
+			want: socketPacket{Type: socketEvent, Namespace: "/", AckID: -1, Data: json.RawMessage(`["query:change",{}]`)}, replies: []string{"3"}},
+		{name: "ping probe is echoed", frames: []string{"2probe", "40"},
+			want: socketPacket{Type: socketConnect, Namespace: "/", AckID: -1}, replies: []string{"3probe"}},
+		{name: "namespaced event with ack id is acknowledged", namespace: "/admin", frames: []string{`42/admin,5["query:change",{}]`},
+			want: socketPacket{Type: socketEvent, Namespace: "/admin", AckID: 5, Data: json.RawMessage(`["query:change",{}]`)}, replies: []string{"43/admin,5[]"}},
+		{name: "other namespaces are skipped", frames: []string{`42/admin,["other"]`, "6", `42["mine"]`},
+			want: socketPacket{Type: socketEvent, Namespace: "/", AckID: -1, Data: json.RawMessage(`["mine"]`)}},
+		{name: "binary event prefix", frames: []string{`451-["upload",{"_placeholder":true,"num":0}]`},
+			want: socketPacket{Type: socketBinaryEvent, Namespace: "/", AckID: -1, Data: json.RawMessage(`["upload",{"_placeholder":true,"num":0}]`)}},
+		{name: "unexpected ack is returned", frames: []string{`433["late"]`},
+			want: socketPacket{Type: socketAck, Namespace: "/", AckID: 3, Data: json.RawMessage(`["late"]`)}},
+		{name: "server disconnect", frames: []string{"41"}, reason: DisconnectServer},
+		{name: "namespace disconnect", namespace: "/admin", frames: []string{"41/admin,"}, reason: DisconnectServer},
+		{name: "connect error", frames: []string{`44{"message":"not authorized"}`}, reason: DisconnectConnectError, message: "not authorized"},
+		{name: "connect error string", frames: []string{`44"invalid namespace"`}, reason: DisconnectConnectError, message: "invalid namespace"},
+		{name: "engine close", frames: []string{"1"}, reason: DisconnectTransportClose},
+		{name: "invalid engine packet", frames: []string{"x"}, reason: DisconnectParseError},
+		{name: "invalid socket packet", frames: []string{"4x"}, reason: DisconnectParseError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn, replies := newSocketPair(t, test.frames)
+			namespace := test.namespace
+			if namespace == "" {
+				namespace = defaultNamespace
+			}
+			c := &socketConn{conn: conn, namespace: namespace}
+			packet, err := c.readPacket()
+			if test.reason != "" {
+				var disconnectErr *DisconnectError
+				if !errors.As(err, &disconnectErr) || disconnectErr.Reason != test.reason || disconnectErr.Message != test.message {
+					t.Fatalf("got %v, want disconnect %q %q", err, test.reason, test.message)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if packet.Type != test.want.Type || packet.Namespace != test.want.Namespace || packet.AckID != test.want.AckID || string(packet.Data) != string(test.want.Data) {
+				t.Fatalf("got %+v, want %+v", packet, test.want)
+			}
+			for _, want := range test.replies {
+				select {
+				case reply := <-replies:
+					if reply != want {
+						t.Fatalf("got reply %s, want %s", reply, want)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatalf("no reply, want %s", want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewSocketConn(t *testing.T) {
+	// current servers send maxPayload even for engine.io v3 sessions
+	open := `0{"sid":"abc","upgrades":[],"pingInterval":10000,"pingTimeout":5000,"maxPayload":1000000}`
+	conn, replies := newSocketPair(t, []string{open})
+	c, err := newSocketConn(conn, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.pingInterval() != 10*time.Second || c.readTimeout() != 15*time.Second {
+		t.Fatalf("got ping interval %v and read timeout %v", c.pingInterval(), c.readTimeout())
+	}
+	// the client pings and does not send a namespace connect
+	if err := c.ping(); err != nil {
+		t.Fatal(err)
+	}
+	if reply := <-replies; reply != "2" {
+		t.Fatalf("got %s, want a ping", reply)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"time"
 
 	"github.com/eventials/go-tus"
 )
@@ -103,26 +104,34 @@ func (client *Client) GetStoragePoolByName(name string) (*StoragePool, error) {
 			return &pool, nil
 		}
 	}
-	return nil, errors.New("storage Pool not found")
+	return nil, notFoundError("storage Pool not found")
 }
 
-// GetStoragePool requests a storage pool by id
-func (client *Client) GetStoragePool(id string) (*StoragePool, error) {
-	if id == "disk" {
+// builtinStoragePool returns the disk and ram storage pools, which are not stored on the server
+func builtinStoragePool(id string) (*StoragePool, bool) {
+	switch id {
+	case "disk":
 		return &StoragePool{
 			ID:   "disk",
 			Name: "disk",
 			Type: "disk",
 			Path: "/zdata",
-		}, nil
-	}
-	if id == "ram" {
+		}, true
+	case "ram":
 		return &StoragePool{
 			ID:   "ram",
 			Name: "ram",
 			Type: "ram",
 			Path: "/zram",
-		}, nil
+		}, true
+	}
+	return nil, false
+}
+
+// GetStoragePool requests a storage pool by id
+func (client *Client) GetStoragePool(id string) (*StoragePool, error) {
+	if pool, ok := builtinStoragePool(id); ok {
+		return pool, nil
 	}
 	pool := &StoragePool{}
 	if id == "" {
@@ -136,6 +145,15 @@ func (client *Client) GetStoragePool(id string) (*StoragePool, error) {
 	return pool, err
 }
 
+// WaitForStoragePoolChange blocks until the storage pool matches a validation function
+func (pool StoragePool) WaitForStoragePoolChange(ctx context.Context, client *Client, timeout time.Duration, validate func(StoragePool) bool) error {
+	if validate(pool) {
+		return nil
+	}
+	_, err := WaitFor(ctx, client, StoragePoolTarget(client, pool.ID), timeout, validate)
+	return err
+}
+
 // Create creates a new storage pool
 func (pool *StoragePool) Create(client *Client) (string, error) {
 	var result string
@@ -338,7 +356,7 @@ func (pool *StoragePool) Browse(client *Client, filePath string, recursive bool)
 
 // Download downloads a file from a storage pool
 func (pool *StoragePool) Download(client *Client, filePath string) (*http.Response, error) {
-	return pool.DownloadWithContext(context.Background(), client, filePath)
+	return pool.DownloadWithContext(client.getContext(), client, filePath)
 }
 
 // DownloadWithContext downloads a file from a storage pool with a custom context
@@ -356,6 +374,11 @@ func (pool *StoragePool) DownloadWithContext(ctx context.Context, client *Client
 
 // Upload uploads a local file into a storage pool
 func (pool *StoragePool) Upload(client *Client, filename, targetFilename string) error {
+	return pool.UploadWithContext(client.getContext(), client, filename, targetFilename)
+}
+
+// UploadWithContext uploads a local file into a storage pool with a custom context
+func (pool *StoragePool) UploadWithContext(ctx context.Context, client *Client, filename, targetFilename string) error {
 	if pool.ID == "" {
 		return errors.New("invalid Storage Pool")
 	}
@@ -365,19 +388,26 @@ func (pool *StoragePool) Upload(client *Client, filename, targetFilename string)
 	}
 	defer f.Close()
 
+	httpClient, err := client.getHTTPClient()
+	if err != nil {
+		return err
+	}
 	header := make(http.Header)
-	header.Add("Authorization", "Bearer "+client.token)
+	header.Add("Authorization", "Bearer "+client.getToken())
 	conf := tus.Config{
 		ChunkSize:           2 * 1024 * 1024,
 		Resume:              false,
 		OverridePatchMethod: false,
 		Store:               nil,
 		Header:              header,
-		HttpClient:          client.httpClient,
+		HttpClient:          httpClient,
 	}
 
-	uploadURL := fmt.Sprintf("https://%s:%d/upload/", client.Host, client.Port)
-	tusClient, err := tus.NewClient(uploadURL, &conf)
+	base, err := client.baseURL()
+	if err != nil {
+		return err
+	}
+	tusClient, err := tus.NewClient(base.String()+"/upload/", &conf)
 	if err != nil {
 		return err
 	}
@@ -394,7 +424,19 @@ func (pool *StoragePool) Upload(client *Client, filename, targetFilename string)
 		return err
 	}
 
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			uploader.Abort()
+		case <-done:
+		}
+	}()
 	err = uploader.Upload()
+	if err == nil && uploader.IsAborted() {
+		err = ctx.Err()
+	}
 	return err
 }
 
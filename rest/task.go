@@ -76,7 +76,7 @@ func (client *Client) GetTaskByName(name string) (*Task, error) {
 			return &task, nil
 		}
 	}
-	return nil, errors.New("Task not found")
+	return nil, notFoundError("Task not found")
 }
 
 // ForceComplete marks a task as completed in the database
@@ -88,21 +88,49 @@ func (task *Task) ForceComplete(client *Client) error {
 	return err
 }
 
+// Cancel asks the appliance to stop a running task. Only tasks with Cancellable set can be cancelled
+func (task *Task) Cancel(client *Client) error {
+	if task.ID == "" {
+		return errors.New("id cannot be empty")
+	}
+	if !task.Cancellable {
+		return errors.New("task is not cancellable")
+	}
+	_, err := client.request("PUT", "task/"+task.ID+"/cancel", nil)
+	return err
+}
+
 // WatchTask monitors a task changefeed and sends updates to taskData
 func (task Task) WatchTask(client *Client, taskData chan Task, errorChannel chan error) {
-	task.WatchTaskWithContext(context.Background(), client, taskData, errorChannel)
+	task.WatchTaskWithContext(client.getContext(), client, taskData, errorChannel)
 }
 
-// WatchTaskWithContext monitors a task changefeed and sends updates to taskData
+// WatchTaskWithContext monitors a task changefeed and sends updates to taskData.
+// Pending sends to taskData and errorChannel are abandoned when ctx is done
 func (task Task) WatchTaskWithContext(ctx context.Context, client *Client, taskData chan Task, errorChannel chan error) {
+	sendError := func(err error) {
+		select {
+		case errorChannel <- err:
+		case <-ctx.Done():
+		}
+	}
 	if task.State == "completed" || task.State == "failed" {
-		taskData <- task
+		select {
+		case taskData <- task:
+		case <-ctx.Done():
+		}
 		return
 	}
 	newVal := Task{}
-	feed, err := client.GetChangeFeedWithContext(ctx, "task", map[string]string{"id": task.ID}, true)
+	feed, err := client.GetChangeFeedWithOptions(ctx, ChangeFeedOptions{
+		Table:                "task",
+		Filter:               map[string]string{"id": task.ID},
+		IncludeInitial:       true,
+		Reconnect:            true,
+		MaxReconnectAttempts: 5,
+	})
 	if err != nil {
-		errorChannel <- err
+		sendError(err)
 		return
 	}
 	defer feed.Close()
@@ -110,15 +138,22 @@ func (task Task) WatchTaskWithContext(ctx context.Context, client *Client, taskD
 		select {
 		case msg := <-feed.Data:
 			if msg.Error != nil {
-				errorChannel <- msg.Error
+				sendError(msg.Error)
 				return
 			}
+			if msg.Reconnected {
+				continue
+			}
 			err = json.Unmarshal(msg.NewValue, &newVal)
 			if err != nil {
-				errorChannel <- err
+				sendError(err)
+				return
+			}
+			select {
+			case taskData <- newVal:
+			case <-ctx.Done():
 				return
 			}
-			taskData <- newVal
 			if newVal.State == "completed" || newVal.State == "failed" {
 				return
 			}
@@ -130,30 +165,21 @@ func (task Task) WatchTaskWithContext(ctx context.Context, client *Client, taskD
 
 //WaitForTask blocks until a task is complete and returns the task
 func (task Task) WaitForTask(client *Client, printProgress bool) (*Task, error) {
-	return task.WaitForTaskWithContext(context.Background(), client, printProgress)
+	return task.WaitForTaskWithContext(client.getContext(), client, printProgress)
 }
 
 //WaitForTask blocks until a task is complete and returns the task
 func (task Task) WaitForTaskWithContext(ctx context.Context, client *Client, printProgress bool) (*Task, error) {
-	var progress float32
-	newVal := task
-	errChannel := make(chan error)
-	taskData := make(chan Task)
-	go task.WatchTaskWithContext(ctx, client, taskData, errChannel)
-	for {
-		select {
-		case <-ctx.Done():
-			return nil, fmt.Errorf("cancelled")
-		case newVal = <-taskData:
-			if printProgress && newVal.Progress != progress {
-				progress = newVal.Progress
-				fmt.Println(newVal.Progress)
-			}
-			if newVal.State == "completed" || newVal.State == "failed" {
-				return &newVal, nil
-			}
-		case err := <-errChannel:
-			return &newVal, err
+	progress := task.Progress
+	newVal, err := WaitFor(ctx, client, TaskTarget(client, task.ID), 0, func(newVal Task) bool {
+		if printProgress && newVal.Progress != progress {
+			progress = newVal.Progress
+			fmt.Println(newVal.Progress)
 		}
+		return newVal.State == "completed" || newVal.State == "failed"
+	})
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("cancelled")
 	}
+	return &newVal, err
 }
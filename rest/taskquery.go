@@ -0,0 +1,275 @@
+package rest
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TaskSortField is a field tasks can be sorted by
+type TaskSortField string
+
+// TaskSortFields supported by TaskQuery.SortBy
+const (
+	TaskSortStartTime    TaskSortField = "startTime"
+	TaskSortFinishedTime TaskSortField = "finishedTime"
+	TaskSortDuration     TaskSortField = "duration"
+	TaskSortName         TaskSortField = "name"
+	TaskSortState        TaskSortField = "state"
+	TaskSortType         TaskSortField = "type"
+)
+
+// TaskQuery builds a query for QueryTasks.
+// Fields the api can filter on are sent as query parameters and every filter is also checked against
+// the returned tasks, so the results are correct even if the api ignores a parameter.
+// When a filter the api cannot apply is set, QueryTasks pages through every task so count and offset apply to the
+// filtered and sorted results. Otherwise count and offset are sent to the api and only that page is sorted.
+// example to list the failed backups of the last day, newest first:
+// rest.NewTaskQuery().State("failed").Type("backup").StartedBetween(time.Now().Add(-24*time.Hour), time.Time{}).SortBy(rest.TaskSortStartTime, true)
+type TaskQuery struct {
+	states         []string
+	taskType       string
+	username       string
+	host           string
+	startedAfter   time.Time
+	startedBefore  time.Time
+	finishedAfter  time.Time
+	finishedBefore time.Time
+	sortBy         TaskSortField
+	descending     bool
+	count          int
+	offset         int
+	filters        url.Values
+}
+
+// NewTaskQuery returns an empty TaskQuery sorted by start time, newest first
+func NewTaskQuery() *TaskQuery {
+	return &TaskQuery{sortBy: TaskSortStartTime, descending: true, filters: url.Values{}}
+}
+
+// State limits the results to tasks in any of states
+func (query *TaskQuery) State(states ...string) *TaskQuery {
+	query.states = append(query.states, states...)
+	return query
+}
+
+// Type limits the results to tasks of taskType
+func (query *TaskQuery) Type(taskType string) *TaskQuery {
+	query.taskType = taskType
+	return query
+}
+
+// Username limits the results to tasks started by username
+func (query *TaskQuery) Username(username string) *TaskQuery {
+	query.username = username
+	return query
+}
+
+// Host limits the results to tasks whose Ref.Host is host
+func (query *TaskQuery) Host(host string) *TaskQuery {
+	query.host = host
+	return query
+}
+
+// StartedBetween limits the results to tasks started in the window. A zero time leaves that end open
+func (query *TaskQuery) StartedBetween(after, before time.Time) *TaskQuery {
+	query.startedAfter, query.startedBefore = after, before
+	return query
+}
+
+// FinishedBetween limits the results to tasks finished in the window. A zero time leaves that end open
+func (query *TaskQuery) FinishedBetween(after, before time.Time) *TaskQuery {
+	query.finishedAfter, query.finishedBefore = after, before
+	return query
+}
+
+// SortBy sets the order of the results
+func (query *TaskQuery) SortBy(field TaskSortField, descending bool) *TaskQuery {
+	query.sortBy, query.descending = field, descending
+	return query
+}
+
+// Limit sets the number of matching tasks returned and the number of matching tasks skipped. A count of 0 returns every match
+func (query *TaskQuery) Limit(count, offset int) *TaskQuery {
+	query.count, query.offset = count, offset
+	return query
+}
+
+// Filter adds a query parameter sent to the api as is
+func (query *TaskQuery) Filter(key, value string) *TaskQuery {
+	query.filters.Add(key, value)
+	return query
+}
+
+// Encode returns the filters sent to the api. QueryTasks adds count and offset
+func (query *TaskQuery) Encode() string {
+	values := url.Values{}
+	for key, value := range query.filters {
+		values[key] = append([]string{}, value...)
+	}
+	if len(query.states) == 1 {
+		values.Set("state", query.states[0])
+	}
+	if query.taskType != "" {
+		values.Set("type", query.taskType)
+	}
+	if query.username != "" {
+		values.Set("username", query.username)
+	}
+	return values.Encode()
+}
+
+// clientFiltered returns true if the query has filters the api cannot apply
+func (query *TaskQuery) clientFiltered() bool {
+	return len(query.states) > 1 || query.host != "" ||
+		!query.startedAfter.IsZero() || !query.startedBefore.IsZero() ||
+		!query.finishedAfter.IsZero() || !query.finishedBefore.IsZero()
+}
+
+func inWindow(t, after, before time.Time) bool {
+	if !after.IsZero() && (t.IsZero() || t.Before(after)) {
+		return false
+	}
+	if !before.IsZero() && (t.IsZero() || t.After(before)) {
+		return false
+	}
+	return true
+}
+
+// Match returns true if task passes every filter of the query
+func (query *TaskQuery) Match(task Task) bool {
+	if len(query.states) > 0 {
+		found := false
+		for _, state := range query.states {
+			if strings.EqualFold(task.State, state) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if query.taskType != "" && task.Type != query.taskType {
+		return false
+	}
+	if query.username != "" && task.Username != query.username {
+		return false
+	}
+	if query.host != "" && task.Ref.Host != query.host {
+		return false
+	}
+	return inWindow(task.StartTime, query.startedAfter, query.startedBefore) &&
+		inWindow(task.FinishedTime, query.finishedAfter, query.finishedBefore)
+}
+
+// Sort orders tasks by the query sort field
+func (query *TaskQuery) Sort(tasks []Task) {
+	less := func(a, b Task) bool {
+		switch query.sortBy {
+		case TaskSortFinishedTime:
+			return a.FinishedTime.Before(b.FinishedTime)
+		case TaskSortDuration:
+			return a.Duration() < b.Duration()
+		case TaskSortName:
+			return a.Name < b.Name
+		case TaskSortState:
+			return a.State < b.State
+		case TaskSortType:
+			return a.Type < b.Type
+		default:
+			return a.StartTime.Before(b.StartTime)
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if query.descending {
+			return less(tasks[j], tasks[i])
+		}
+		return less(tasks[i], tasks[j])
+	})
+}
+
+// taskPageSize is the number of tasks requested for each page by QueryTasks
+const taskPageSize = 500
+
+// QueryTasks returns the tasks matching query in the query order.
+// With filters the api cannot apply every page of tasks is requested before filtering and sorting,
+// then count and offset select the results
+func (client *Client) QueryTasks(query *TaskQuery) ([]Task, error) {
+	filters := query.Encode()
+	if !query.clientFiltered() {
+		values, _ := url.ParseQuery(filters)
+		if query.count > 0 {
+			values.Set("count", strconv.Itoa(query.count))
+		}
+		if query.offset > 0 {
+			values.Set("offset", strconv.Itoa(query.offset))
+		}
+		tasks, err := client.ListTasks(values.Encode())
+		if err != nil {
+			return nil, err
+		}
+		matched := make([]Task, 0, len(tasks))
+		for _, task := range tasks {
+			if query.Match(task) {
+				matched = append(matched, task)
+			}
+		}
+		query.Sort(matched)
+		return matched, nil
+	}
+	if filters != "" {
+		filters += "&"
+	}
+	matched := []Task{}
+	firstID := ""
+	for offset := 0; ; offset += taskPageSize {
+		tasks, err := client.ListTasks(filters + "count=" + strconv.Itoa(taskPageSize) + "&offset=" + strconv.Itoa(offset))
+		if err != nil {
+			return nil, err
+		}
+		// stop if the api ignored offset and returned the first page again
+		if offset > 0 && len(tasks) > 0 && tasks[0].ID == firstID {
+			break
+		}
+		if offset == 0 && len(tasks) > 0 {
+			firstID = tasks[0].ID
+		}
+		for _, task := range tasks {
+			if query.Match(task) {
+				matched = append(matched, task)
+			}
+		}
+		// a short page is the last one, a long page means the api ignored count and returned everything
+		if len(tasks) != taskPageSize {
+			break
+		}
+	}
+	query.Sort(matched)
+	if query.offset > 0 {
+		if query.offset >= len(matched) {
+			return []Task{}, nil
+		}
+		matched = matched[query.offset:]
+	}
+	if query.count > 0 && query.count < len(matched) {
+		matched = matched[:query.count]
+	}
+	return matched, nil
+}
+
+// Duration returns how long the task ran, or has been running if it has not finished
+func (task Task) Duration() time.Duration {
+	switch {
+	case task.StartTime.IsZero():
+		return 0
+	case !task.FinishedTime.IsZero() && !task.FinishedTime.Before(task.StartTime):
+		return task.FinishedTime.Sub(task.StartTime)
+	case (task.State == "completed" || task.State == "failed") && !task.LastUpdatedTime.IsZero():
+		return task.LastUpdatedTime.Sub(task.StartTime)
+	default:
+		return time.Since(task.StartTime)
+	}
+}
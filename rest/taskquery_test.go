@@ -0,0 +1,91 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestQueryTasksPagesBeforeLimiting(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tasks := make([]Task, 1200)
+	for i := range tasks {
+		tasks[i] = Task{ID: strconv.Itoa(i), State: "completed", StartTime: start.Add(time.Duration(i) * time.Minute)}
+		if i%100 == 0 {
+			tasks[i].State = "failed"
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tasks" {
+			http.NotFound(w, r)
+			return
+		}
+		count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		end := min(offset+count, len(tasks))
+		if offset > end {
+			offset = end
+		}
+		json.NewEncoder(w).Encode(tasks[offset:end])
+	}))
+	defer srv.Close()
+	client := &Client{BaseURL: srv.URL}
+
+	// the failed tasks are spread over every page and filtered client side
+	query := NewTaskQuery().State("failed", "cancelled").SortBy(TaskSortStartTime, true).Limit(3, 1)
+	result, err := client.QueryTasks(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := fmt.Sprint(taskIDs(result))
+	if ids != "[1000 900 800]" {
+		t.Fatalf("got %s, want [1000 900 800]", ids)
+	}
+
+	result, err = client.QueryTasks(NewTaskQuery().StartedBetween(start, time.Time{}).SortBy(TaskSortStartTime, false).Limit(0, 1190))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 10 || result[0].ID != "1190" {
+		t.Fatalf("got %d tasks starting at %v", len(result), taskIDs(result))
+	}
+}
+
+func taskIDs(tasks []Task) []string {
+	ids := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+func TestQueryTasksSendsLimitWithoutClientFilters(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	requests := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery)
+		tasks := []Task{}
+		for i := 0; i < 3; i++ {
+			tasks = append(tasks, Task{ID: strconv.Itoa(i), Type: "backup", StartTime: start.Add(time.Duration(i) * time.Minute)})
+		}
+		json.NewEncoder(w).Encode(tasks)
+	}))
+	defer srv.Close()
+	client := &Client{BaseURL: srv.URL}
+
+	result, err := client.QueryTasks(NewTaskQuery().Type("backup").Limit(3, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 || requests[0] != "count=3&offset=10&type=backup" {
+		t.Fatalf("got requests %v", requests)
+	}
+	// newest first by default
+	if ids := fmt.Sprint(taskIDs(result)); ids != "[2 1 0]" {
+		t.Fatalf("got %s, want [2 1 0]", ids)
+	}
+}
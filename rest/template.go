@@ -1,9 +1,11 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/url"
+	"time"
 )
 
 // TemplateInterface a network interface from a template record
@@ -82,6 +84,15 @@ func (client *Client) GetTemplate(name string) (Template, error) {
 	return template, err
 }
 
+// WaitForTemplateChange blocks until the template matches a validation function
+func (template Template) WaitForTemplateChange(ctx context.Context, client *Client, timeout time.Duration, validate func(Template) bool) error {
+	if validate(template) {
+		return nil
+	}
+	_, err := WaitFor(ctx, client, TemplateTarget(client, template.Name), timeout, validate)
+	return err
+}
+
 // Create creates a new template
 func (template *Template) Create(client *Client) (string, error) {
 	var result string
@@ -0,0 +1,72 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// normalizeFingerprint strips separators from a hex encoded sha256 fingerprint
+func normalizeFingerprint(fingerprint string) string {
+	fingerprint = strings.ReplaceAll(fingerprint, ":", "")
+	fingerprint = strings.ReplaceAll(fingerprint, " ", "")
+	return strings.ToLower(fingerprint)
+}
+
+// CertificateFingerprint returns the hex encoded sha256 fingerprint of a certificate
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// tlsConfig builds the tls configuration shared by rest requests, change feeds and uploads
+func (client *Client) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: client.AllowInsecure}
+	if client.CAFile != "" {
+		data, err := os.ReadFile(client.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", client.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	if client.ClientCertFile != "" || client.ClientKeyFile != "" {
+		if client.ClientCertFile == "" || client.ClientKeyFile == "" {
+			return nil, errors.New("client certificate and key must both be provided")
+		}
+		cert, err := tls.LoadX509KeyPair(client.ClientCertFile, client.ClientKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if len(client.PinnedFingerprints) > 0 {
+		pins := map[string]bool{}
+		for _, fingerprint := range client.PinnedFingerprints {
+			fingerprint = normalizeFingerprint(fingerprint)
+			if len(fingerprint) != sha256.Size*2 {
+				return nil, fmt.Errorf("invalid sha256 fingerprint: %s", fingerprint)
+			}
+			pins[fingerprint] = true
+		}
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("server did not provide a certificate")
+			}
+			fingerprint := CertificateFingerprint(state.PeerCertificates[0])
+			if !pins[fingerprint] {
+				return fmt.Errorf("certificate fingerprint %s does not match a pinned fingerprint", fingerprint)
+			}
+			return nil
+		}
+	}
+	return config, nil
+}
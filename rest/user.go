@@ -69,7 +69,7 @@ func (client *Client) GetUserByName(name string) (*User, error) {
 			return &user, nil
 		}
 	}
-	return nil, errors.New("User not found")
+	return nil, notFoundError("User not found")
 }
 
 // GetUserByGroupName request a user by groupname
@@ -83,7 +83,7 @@ func (client *Client) GetUserByGroupName(name string) (*User, error) {
 			return &user, nil
 		}
 	}
-	return nil, errors.New("group not found")
+	return nil, notFoundError("group not found")
 }
 
 // Create creates a new user
@@ -0,0 +1,324 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// ErrWaitTimeout is returned when a wait does not finish before its timeout
+var ErrWaitTimeout = errors.New("timed out")
+
+const defaultPollInterval = 5 * time.Second
+
+// WaitTarget describes the records a wait observes, both through a change feed and through the rest api
+type WaitTarget[T any] struct {
+	// Table and Filter select the change feed for the records
+	Table  string
+	Filter map[string]string
+	// List returns the current records matching Filter. A missing record should return an empty list
+	List func(ctx context.Context) ([]T, error)
+	// Key returns a unique key for a record
+	Key func(T) string
+	// PollInterval is how often List is called when the change feed is unavailable. Defaults to 5s
+	PollInterval time.Duration
+}
+
+// WaitFor blocks until the record selected by target satisfies predicate and returns it.
+// A timeout <= 0 waits until ctx is done.
+func WaitFor[T any](ctx context.Context, client *Client, target WaitTarget[T], timeout time.Duration, predicate func(T) bool) (T, error) {
+	return waitForAfter(ctx, client, target, timeout, nil, predicate)
+}
+
+// waitForAfter is WaitFor calling start once the change feed is open, so changes caused by start are not missed
+func waitForAfter[T any](ctx context.Context, client *Client, target WaitTarget[T], timeout time.Duration, start func(context.Context) error, predicate func(T) bool) (T, error) {
+	records, err := waitForAllAfter(ctx, client, target, timeout, start, func(records []T) bool {
+		return len(records) > 0 && predicate(records[0])
+	})
+	if len(records) > 0 {
+		return records[0], err
+	}
+	var zero T
+	return zero, err
+}
+
+// WaitForAll blocks until the set of records selected by target satisfies predicate and returns the set sorted by key.
+// The set is loaded with target.List and kept up to date with a change feed. If the change feed cannot be opened,
+// for example because websockets are blocked, or stops, the set is polled with target.List instead.
+// A timeout <= 0 waits until ctx is done.
+func WaitForAll[T any](ctx context.Context, client *Client, target WaitTarget[T], timeout time.Duration, predicate func([]T) bool) ([]T, error) {
+	return waitForAllAfter(ctx, client, target, timeout, nil, predicate)
+}
+
+// waitForAllAfter is WaitForAll calling start once the change feed is open and the set is loaded.
+// start is called with the wait context, which includes the timeout. An error from start is returned as is
+func waitForAllAfter[T any](ctx context.Context, client *Client, target WaitTarget[T], timeout time.Duration, start func(context.Context) error, predicate func([]T) bool) ([]T, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, ErrWaitTimeout)
+		defer cancel()
+	}
+	w := &waiter[T]{target: target, records: make(map[string]T), start: start}
+	records, err := w.wait(ctx, client, predicate)
+	if err != nil && !w.startFailed && ctx.Err() != nil {
+		err = context.Cause(ctx)
+	}
+	return records, err
+}
+
+// waiter holds the current set of records for WaitForAll
+type waiter[T any] struct {
+	target      WaitTarget[T]
+	records     map[string]T
+	start       func(context.Context) error
+	startFailed bool
+}
+
+// sorted returns the records ordered by key
+func (w *waiter[T]) sorted() []T {
+	keys := make([]string, 0, len(w.records))
+	for key := range w.records {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	records := make([]T, 0, len(keys))
+	for _, key := range keys {
+		records = append(records, w.records[key])
+	}
+	return records
+}
+
+// reload replaces the set with the records returned by target.List
+func (w *waiter[T]) reload(ctx context.Context) error {
+	records, err := w.target.List(ctx)
+	if err != nil {
+		return err
+	}
+	w.records = make(map[string]T, len(records))
+	for _, record := range records {
+		w.records[w.target.Key(record)] = record
+	}
+	return nil
+}
+
+// apply updates the set with a change from the change feed
+func (w *waiter[T]) apply(change Change[T]) {
+	switch {
+	case change.New != nil:
+		w.records[w.target.Key(*change.New)] = *change.New
+	case change.Old != nil:
+		delete(w.records, w.target.Key(*change.Old))
+	}
+}
+
+func (w *waiter[T]) wait(ctx context.Context, client *Client, predicate func([]T) bool) ([]T, error) {
+	feed, feedErr := client.GetChangeFeedWithOptions(ctx, ChangeFeedOptions{
+		Table:                w.target.Table,
+		Filter:               w.target.Filter,
+		Reconnect:            true,
+		MaxReconnectAttempts: 5,
+	})
+	if feedErr == nil {
+		defer feed.Close()
+	}
+	if err := w.reload(ctx); err != nil {
+		return nil, err
+	}
+	if w.start != nil {
+		if err := w.start(ctx); err != nil {
+			w.startFailed = true
+			return nil, err
+		}
+	}
+	if records := w.sorted(); predicate(records) {
+		return records, nil
+	}
+
+	if feedErr == nil {
+	feedLoop:
+		for {
+			select {
+			case <-ctx.Done():
+				return w.sorted(), ctx.Err()
+			case <-feed.Done:
+				break feedLoop
+			case msg := <-feed.Data:
+				if msg.Error != nil {
+					// the feed either recovers from a bad packet or stops and closes Done
+					feedErr = msg.Error
+					continue
+				}
+				if msg.Reconnected {
+					// changes may have been missed while disconnected
+					if err := w.reload(ctx); err != nil {
+						return w.sorted(), err
+					}
+				} else {
+					change, err := decodeChange[T](msg)
+					if err != nil {
+						return w.sorted(), err
+					}
+					w.apply(change)
+				}
+				if records := w.sorted(); predicate(records) {
+					return records, nil
+				}
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return w.sorted(), ctx.Err()
+	}
+	return w.poll(ctx, client, predicate, feedErr)
+}
+
+// poll calls target.List until the set satisfies predicate
+func (w *waiter[T]) poll(ctx context.Context, client *Client, predicate func([]T) bool, cause error) ([]T, error) {
+	if client.logEnabled(ctx) && cause != nil {
+		client.Logger.LogAttrs(ctx, slog.LevelDebug, "change feed unavailable, polling",
+			slog.String("table", w.target.Table), slog.String("cause", cause.Error()))
+	}
+	interval := w.target.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return w.sorted(), ctx.Err()
+		case <-ticker.C:
+		}
+		if err := w.reload(ctx); err != nil {
+			if ctx.Err() != nil {
+				return w.sorted(), ctx.Err()
+			}
+			return w.sorted(), err
+		}
+		if records := w.sorted(); predicate(records) {
+			return records, nil
+		}
+	}
+}
+
+// getRecord requests a single record with ctx, treating not found as an empty list
+func getRecord[T any](ctx context.Context, client *Client, path, id string) ([]T, error) {
+	if id == "" {
+		return nil, errors.New("id cannot be empty")
+	}
+	body, err := client.requestWithContext(ctx, "GET", path+url.PathEscape(id), nil)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record T
+	if err := json.Unmarshal(body, &record); err != nil {
+		return nil, err
+	}
+	return []T{record}, nil
+}
+
+// listRecords requests a list of records with ctx
+func listRecords[T any](ctx context.Context, client *Client, path string) ([]T, error) {
+	body, err := client.requestWithContext(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var records []T
+	err = json.Unmarshal(body, &records)
+	return records, err
+}
+
+// GuestTarget selects a guest by name
+func GuestTarget(client *Client, name string) WaitTarget[Guest] {
+	return WaitTarget[Guest]{
+		Table:  "guest",
+		Filter: map[string]string{"name": name},
+		List: func(ctx context.Context) ([]Guest, error) {
+			return getRecord[Guest](ctx, client, "guest/", name)
+		},
+		Key: func(guest Guest) string { return guest.Name },
+	}
+}
+
+// PoolGuestsTarget selects all guests in a pool
+func PoolGuestsTarget(client *Client, poolID string) WaitTarget[Guest] {
+	return WaitTarget[Guest]{
+		Table:  "guest",
+		Filter: map[string]string{"poolId": poolID},
+		List: func(ctx context.Context) ([]Guest, error) {
+			return listRecords[Guest](ctx, client, "guests?poolId="+url.QueryEscape(poolID))
+		},
+		Key: func(guest Guest) string { return guest.Name },
+	}
+}
+
+// PoolTarget selects a guest pool by id
+func PoolTarget(client *Client, id string) WaitTarget[Pool] {
+	return WaitTarget[Pool]{
+		Table:  "pool",
+		Filter: map[string]string{"id": id},
+		List: func(ctx context.Context) ([]Pool, error) {
+			return getRecord[Pool](ctx, client, "pool/", id)
+		},
+		Key: func(pool Pool) string { return pool.ID },
+	}
+}
+
+// HostTarget selects a host by hostid
+func HostTarget(client *Client, hostid string) WaitTarget[Host] {
+	return WaitTarget[Host]{
+		Table:  "host",
+		Filter: map[string]string{"hostid": hostid},
+		List: func(ctx context.Context) ([]Host, error) {
+			return getRecord[Host](ctx, client, "host/", hostid)
+		},
+		Key: func(host Host) string { return host.Hostid },
+	}
+}
+
+// TemplateTarget selects a template by name
+func TemplateTarget(client *Client, name string) WaitTarget[Template] {
+	return WaitTarget[Template]{
+		Table:  "template",
+		Filter: map[string]string{"name": name},
+		List: func(ctx context.Context) ([]Template, error) {
+			return getRecord[Template](ctx, client, "template/", name)
+		},
+		Key: func(template Template) string { return template.Name },
+	}
+}
+
+// StoragePoolTarget selects a storage pool by id
+func StoragePoolTarget(client *Client, id string) WaitTarget[StoragePool] {
+	return WaitTarget[StoragePool]{
+		Table:  "storagePool",
+		Filter: map[string]string{"id": id},
+		List: func(ctx context.Context) ([]StoragePool, error) {
+			if pool, ok := builtinStoragePool(id); ok {
+				return []StoragePool{*pool}, nil
+			}
+			return getRecord[StoragePool](ctx, client, "storage/pool/", id)
+		},
+		Key: func(pool StoragePool) string { return pool.ID },
+	}
+}
+
+// TaskTarget selects a task by id
+func TaskTarget(client *Client, id string) WaitTarget[Task] {
+	return WaitTarget[Task]{
+		Table:  "task",
+		Filter: map[string]string{"id": id},
+		List: func(ctx context.Context) ([]Task, error) {
+			return getRecord[Task](ctx, client, "task/", id)
+		},
+		Key: func(task Task) string { return task.ID },
+	}
+}
@@ -0,0 +1,177 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ChangeType describes how a record was changed
+type ChangeType string
+
+// ChangeTypes delivered by a TypedChangeFeed
+const (
+	ChangeInitial     ChangeType = "initial"
+	ChangeInsert      ChangeType = "insert"
+	ChangeUpdate      ChangeType = "update"
+	ChangeDelete      ChangeType = "delete"
+	ChangeReconnected ChangeType = "reconnected"
+)
+
+// Change is a decoded change feed message. Old is nil for inserts and initial values, New is nil for deletes.
+// A ChangeReconnected change has neither and means changes may have been missed while disconnected.
+type Change[T any] struct {
+	Type ChangeType
+	Old  *T
+	New  *T
+}
+
+// TypedChangeFeed decodes the records from a ChangeFeed into T.
+// Changes and Errors are closed when the underlying feed stops.
+// Errors buffers up to ErrorBufferSize unread errors. Once it is full the feed waits for Errors to be read
+// before delivering more changes, so a consumer must read both channels.
+type TypedChangeFeed[T any] struct {
+	Changes <-chan Change[T]
+	Errors  <-chan error
+	feed    *ChangeFeed
+}
+
+// ErrorBufferSize is the number of unread errors a TypedChangeFeed holds before it stops delivering changes
+const ErrorBufferSize = 16
+
+// Close disconnects the underlying change feed
+func (feed *TypedChangeFeed[T]) Close() error {
+	return feed.feed.Close()
+}
+
+// Done is closed when the underlying change feed stops
+func (feed *TypedChangeFeed[T]) Done() <-chan struct{} {
+	return feed.feed.Done
+}
+
+func isNullValue(value json.RawMessage) bool {
+	value = bytes.TrimSpace(value)
+	return len(value) == 0 || bytes.Equal(value, []byte("null"))
+}
+
+// decodeChange converts a ChangeFeedMessage into a Change[T]
+func decodeChange[T any](msg ChangeFeedMessage) (Change[T], error) {
+	var change Change[T]
+	if msg.Reconnected {
+		change.Type = ChangeReconnected
+		return change, nil
+	}
+	if !isNullValue(msg.OldValue) {
+		change.Old = new(T)
+		if err := json.Unmarshal(msg.OldValue, change.Old); err != nil {
+			return change, fmt.Errorf("error decoding old_val: %w", err)
+		}
+	}
+	if !isNullValue(msg.NewValue) {
+		change.New = new(T)
+		if err := json.Unmarshal(msg.NewValue, change.New); err != nil {
+			return change, fmt.Errorf("error decoding new_val: %w", err)
+		}
+	}
+	switch {
+	case msg.Initial:
+		change.Type = ChangeInitial
+	case change.Old == nil && change.New != nil:
+		change.Type = ChangeInsert
+	case change.Old != nil && change.New == nil:
+		change.Type = ChangeDelete
+	default:
+		change.Type = ChangeUpdate
+	}
+	return change, nil
+}
+
+// Watch returns a TypedChangeFeed that decodes the records of options.Table into T
+func Watch[T any](ctx context.Context, client *Client, options ChangeFeedOptions) (*TypedChangeFeed[T], error) {
+	feed, err := client.GetChangeFeedWithOptions(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	return watchFeed[T](feed), nil
+}
+
+// watchFeed decodes the messages of feed into T
+func watchFeed[T any](feed *ChangeFeed) *TypedChangeFeed[T] {
+	changes := make(chan Change[T])
+	errs := make(chan error, ErrorBufferSize)
+	go func() {
+		defer close(changes)
+		defer close(errs)
+		// sendError returns false if the feed stopped while Errors was full
+		sendError := func(err error) bool {
+			select {
+			case errs <- err:
+				return true
+			case <-feed.Done:
+				return false
+			}
+		}
+		for {
+			var msg ChangeFeedMessage
+			select {
+			case <-feed.Done:
+				return
+			case msg = <-feed.Data:
+			}
+			if msg.Error != nil {
+				if !sendError(msg.Error) {
+					return
+				}
+				continue
+			}
+			change, err := decodeChange[T](msg)
+			if err != nil {
+				if !sendError(err) {
+					return
+				}
+				continue
+			}
+			select {
+			case changes <- change:
+			case <-feed.Done:
+				return
+			}
+		}
+	}()
+	return &TypedChangeFeed[T]{Changes: changes, Errors: errs, feed: feed}
+}
+
+func watchOptions(table string, filter map[string]string) ChangeFeedOptions {
+	return ChangeFeedOptions{Table: table, Filter: filter, Reconnect: true}
+}
+
+// WatchGuests returns a TypedChangeFeed for guests matching filter
+func WatchGuests(ctx context.Context, client *Client, filter map[string]string) (*TypedChangeFeed[Guest], error) {
+	return Watch[Guest](ctx, client, watchOptions("guest", filter))
+}
+
+// WatchPools returns a TypedChangeFeed for guest pools matching filter
+func WatchPools(ctx context.Context, client *Client, filter map[string]string) (*TypedChangeFeed[Pool], error) {
+	return Watch[Pool](ctx, client, watchOptions("pool", filter))
+}
+
+// WatchTasks returns a TypedChangeFeed for tasks matching filter
+func WatchTasks(ctx context.Context, client *Client, filter map[string]string) (*TypedChangeFeed[Task], error) {
+	return Watch[Task](ctx, client, watchOptions("task", filter))
+}
+
+// WatchHosts returns a TypedChangeFeed for hosts matching filter
+func WatchHosts(ctx context.Context, client *Client, filter map[string]string) (*TypedChangeFeed[Host], error) {
+	return Watch[Host](ctx, client, watchOptions("host", filter))
+}
+
+// WatchAlerts returns a TypedChangeFeed for alerts matching filter
+func WatchAlerts(ctx context.Context, client *Client, filter map[string]string) (*TypedChangeFeed[Alert], error) {
+	return Watch[Alert](ctx, client, watchOptions("alert", filter))
+}
+
+// WatchStoragePools returns a TypedChangeFeed for storage pools matching filter
+func WatchStoragePools(ctx context.Context, client *Client, filter map[string]string) (*TypedChangeFeed[StoragePool], error) {
+	return Watch[StoragePool](ctx, client, watchOptions("storagePool", filter))
+}
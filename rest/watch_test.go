@@ -0,0 +1,46 @@
+package rest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestWatchFeedKeepsEveryError(t *testing.T) {
+	feed := &ChangeFeed{Data: make(chan ChangeFeedMessage), Done: make(chan struct{})}
+	defer close(feed.Done)
+	typed := watchFeed[Guest](feed)
+
+	const errorCount = ErrorBufferSize + 4
+	go func() {
+		for i := 0; i < errorCount; i++ {
+			feed.Data <- ChangeFeedMessage{NewValue: json.RawMessage(`{"name":1}`)}
+		}
+		feed.Data <- ChangeFeedMessage{NewValue: json.RawMessage(`{"name":"guest1"}`)}
+	}()
+
+	// the change waits until the errors in front of it are read
+	select {
+	case change := <-typed.Changes:
+		t.Fatalf("change %+v delivered while Errors was full", change)
+	case <-time.After(50 * time.Millisecond):
+	}
+	for i := 0; i < errorCount; i++ {
+		select {
+		case err := <-typed.Errors:
+			if err == nil {
+				t.Fatal("nil error")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("error %d was not delivered", i)
+		}
+	}
+	select {
+	case change := <-typed.Changes:
+		if change.Type != ChangeInsert || change.New.Name != "guest1" {
+			t.Fatalf("got %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("change was not delivered")
+	}
+}